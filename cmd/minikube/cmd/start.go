@@ -25,7 +25,6 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
-	"os/user"
 	"regexp"
 	"runtime"
 	"sort"
@@ -954,12 +953,6 @@ var checkRepository = func(v semver.Version, repo string) error {
 
 // validateUser validates minikube is run by the recommended user (privileged or regular)
 func validateUser(drvName string) {
-	u, err := user.Current()
-	if err != nil {
-		klog.Errorf("Error getting the current user: %v", err)
-		return
-	}
-
 	useForce := viper.GetBool(force)
 
 	// None driver works with root and without root on Linux
@@ -973,8 +966,8 @@ func validateUser(drvName string) {
 		return
 	}
 
-	// If we are not root, exit early
-	if u.Uid != "0" {
+	// If we are not root, exit early. Elevated administrators are expected on windows, e.g. by the hyperv driver.
+	if runtime.GOOS == "windows" || !detect.IsElevated() {
 		return
 	}
 
@@ -983,7 +976,7 @@ func validateUser(drvName string) {
 	out.ErrT(style.Documentation, "  {{.url}}", out.V{"url": "https://minikube.sigs.k8s.io/docs/reference/drivers/none/"})
 
 	cname := ClusterFlagValue()
-	_, err = config.Load(cname)
+	_, err := config.Load(cname)
 	if err == nil || !config.IsNotExist(err) {
 		out.ErrT(style.Tip, "Tip: To remove this root owned cluster, run: sudo {{.cmd}}", out.V{"cmd": mustload.ExampleCmd(cname, "delete")})
 	}
@@ -24,7 +24,6 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"syscall"
 
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
@@ -34,6 +33,7 @@ import (
 	"github.com/pkg/errors"
 
 	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/util"
 )
 
@@ -141,7 +141,9 @@ func MakeDiskImage(d *drivers.BaseDriver, boot2dockerURL string, diskSize int) e
 
 func fixMachinePermissions(path string) error {
 	klog.Infof("Fixing permissions on %s ...", path)
-	if err := os.Chown(path, syscall.Getuid(), syscall.Getegid()); err != nil {
+	// under sudo, hand the machine back to the invoking user
+	uid, gid := detect.EffectiveUIDGID()
+	if err := os.Chown(path, uid, gid); err != nil {
 		return errors.Wrap(err, "chown dir")
 	}
 	files, err := os.ReadDir(path)
@@ -150,7 +152,7 @@ func fixMachinePermissions(path string) error {
 	}
 	for _, f := range files {
 		fp := filepath.Join(path, f.Name())
-		if err := os.Chown(fp, syscall.Getuid(), syscall.Getegid()); err != nil {
+		if err := os.Chown(fp, uid, gid); err != nil {
 			return errors.Wrap(err, "chown file")
 		}
 	}
@@ -100,6 +100,8 @@ const (
 	TestDiskAvailableEnv = "MINIKUBE_TEST_AVAILABLE_STORAGE"
 	// MinikubeRootlessEnv is used to force Rootless Docker/Podman driver
 	MinikubeRootlessEnv = "MINIKUBE_ROOTLESS"
+	// MinikubeCacheDirEnv is used to override the directory minikube caches images and ISOs in
+	MinikubeCacheDirEnv = "MINIKUBE_CACHE_DIR"
 
 	// scheduled stop constants
 
@@ -0,0 +1,783 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/fslock"
+	"github.com/juju/mutex"
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// cacheDirConfigKey is the config key overriding the cache directory, viper binds it to MINIKUBE_CACHE_DIR too
+const cacheDirConfigKey = "cache-dir"
+
+// cacheDirChecks memoizes the ValidateCacheDir result of each override, as the cache dirs are looked up per file
+var cacheDirChecks sync.Map
+
+// CacheDir returns the base directory of the image, kic and ISO caches. It is the cache directory in the minikube home
+// unless overridden by MINIKUBE_CACHE_DIR or the cache-dir config key, which lets CI share a pre-warmed volume.
+// An override that fails ValidateCacheDir is ignored with a warning.
+func CacheDir() string {
+	dir := os.Getenv(constants.MinikubeCacheDirEnv)
+	if dir == "" {
+		dir = viper.GetString(cacheDirConfigKey)
+	}
+	if dir == "" {
+		return localpath.MakeMiniPath("cache")
+	}
+
+	check, ok := cacheDirChecks.Load(dir)
+	if !ok {
+		err := ValidateCacheDir(dir)
+		if err != nil {
+			klog.Warningf("ignoring cache directory override: %v", err)
+		}
+		check, _ = cacheDirChecks.LoadOrStore(dir, err)
+	}
+	if check != nil {
+		return localpath.MakeMiniPath("cache")
+	}
+	return dir
+}
+
+// ValidateCacheDir returns an error if dir cannot be used as the cache directory: it must be absolute and writable.
+// The directory is created if it does not exist.
+func ValidateCacheDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("cache directory %q is not an absolute path", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".write-test-")
+	if err != nil {
+		return fmt.Errorf("cache directory %q is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// cacheLockTimeout bounds how long AcquireCacheLock waits for another minikube to finish maintaining the cache
+var cacheLockTimeout = 10 * time.Minute
+
+// AcquireCacheLock takes the exclusive lock of the cache that operations rewriting it as a whole, such as ClearCache and
+// PruneImageCache, hold. Downloads only hold the lock of the artifact they write, which those operations also take
+// before removing one of its files. The lock file lives in CacheDir, so it also excludes minikube processes on other
+// hosts or containers sharing a cache volume. The returned func releases the lock.
+func AcquireCacheLock() (unlock func(), err error) {
+	dir := CacheDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	l := fslock.New(filepath.Join(dir, ".cache.lock"))
+	klog.Infof("acquiring cache lock in %s", dir)
+	if err := l.LockWithTimeout(cacheLockTimeout); err != nil {
+		return nil, fmt.Errorf("acquire cache lock in %s: %w", dir, err)
+	}
+	return func() {
+		if err := l.Unlock(); err != nil {
+			klog.Warningf("failed to release cache lock: %v", err)
+		}
+	}, nil
+}
+
+// ImageCacheDir returns the path to the container image cache for the current architecture
+func ImageCacheDir() string {
+	return cacheDirForArch(CacheImages, runtime.GOARCH)
+}
+
+// KICCacheDir returns the path to the container node cache for the current architecture
+func KICCacheDir() string {
+	return cacheDirForArch(CacheKIC, runtime.GOARCH)
+}
+
+// ISOCacheDir returns the path to the virtual machine image cache for the current architecture
+func ISOCacheDir() string {
+	return cacheDirForArch(CacheISO, runtime.GOARCH)
+}
+
+// ImageCacheDirForArch returns the path to the container image cache for arch, which must be a supported architecture,
+// so that images for another architecture can be preloaded
+func ImageCacheDirForArch(arch string) (string, error) {
+	return archCacheDir(CacheImages, arch)
+}
+
+// KICCacheDirForArch returns the path to the container node cache for arch, which must be a supported architecture
+func KICCacheDirForArch(arch string) (string, error) {
+	return archCacheDir(CacheKIC, arch)
+}
+
+// ISOCacheDirForArch returns the path to the virtual machine image cache for arch, which must be a supported architecture
+func ISOCacheDirForArch(arch string) (string, error) {
+	return archCacheDir(CacheISO, arch)
+}
+
+func archCacheDir(kind CacheKind, arch string) (string, error) {
+	if err := validateArch(arch); err != nil {
+		return "", err
+	}
+	return cacheDirForArch(kind, arch), nil
+}
+
+// cacheDirForArch returns the path to the kind cache for arch
+func cacheDirForArch(kind CacheKind, arch string) string {
+	return filepath.Join(CacheDir(), string(kind), arch)
+}
+
+// ImageCacheDirForProfile returns the path to the container image cache of profile for the current architecture.
+// The default profile uses the global ImageCacheDir.
+func ImageCacheDirForProfile(profile string) (string, error) {
+	return profileCacheDir(ImageCacheDir(), profile)
+}
+
+// KICCacheDirForProfile returns the path to the container node cache of profile for the current architecture.
+// The default profile uses the global KICCacheDir.
+func KICCacheDirForProfile(profile string) (string, error) {
+	return profileCacheDir(KICCacheDir(), profile)
+}
+
+// ISOCacheDirForProfile returns the path to the virtual machine image cache of profile for the current architecture.
+// The default profile uses the global ISOCacheDir.
+func ISOCacheDirForProfile(profile string) (string, error) {
+	return profileCacheDir(ISOCacheDir(), profile)
+}
+
+// profilesCacheDir holds the per-profile caches below a global cache dir. Image references cannot start with "_",
+// so it cannot collide with the directory of a cached image.
+const profilesCacheDir = "_profiles"
+
+// profileCacheDir returns the directory of profile nested under the global cache dir
+func profileCacheDir(dir, profile string) (string, error) {
+	if err := validateCacheProfile(profile); err != nil {
+		return "", err
+	}
+	if profile == constants.DefaultClusterName {
+		return dir, nil
+	}
+	return filepath.Join(dir, profilesCacheDir, profile), nil
+}
+
+// validateCacheProfile returns an error if profile cannot safely be used as a single path element
+func validateCacheProfile(profile string) error {
+	if profile == "" || profile == "." || profile == ".." || strings.ContainsAny(profile, `/\:`) || filepath.Base(profile) != profile {
+		return fmt.Errorf("invalid profile name %q for a cache directory", profile)
+	}
+	return nil
+}
+
+// CacheKind is a cache minikube keeps below CacheDir
+type CacheKind string
+
+// CacheKind values, named after their directory in CacheDir
+const (
+	CacheImages CacheKind = "images"
+	CacheKIC    CacheKind = "kic"
+	CacheISO    CacheKind = "iso"
+	// CacheAll stands for all of the caches
+	CacheAll CacheKind = "all"
+)
+
+// cacheKinds are the caches below CacheDir
+var cacheKinds = []CacheKind{CacheImages, CacheKIC, CacheISO}
+
+// CacheDirSize returns the total size in bytes of the files in the image, kic and ISO caches of every architecture.
+// Caches that do not exist yet count as empty, and files that cannot be read are skipped with a warning.
+func CacheDirSize() (int64, error) {
+	var total int64
+	for _, kind := range cacheKinds {
+		size, err := dirSize(filepath.Join(CacheDir(), string(kind)))
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// dirSize returns the total size in bytes of the regular files below root, which may not exist
+func dirSize(root string) (int64, error) {
+	root = resolvedDir(root)
+	var size int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			klog.Warningf("skipping %s while sizing the cache: %v", path, err)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			klog.Warningf("skipping %s while sizing the cache: %v", path, err)
+			return nil
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("size %s: %w", root, err)
+	}
+	return size, nil
+}
+
+// PruneImageCache deletes the cached image tarballs of the current architecture last modified before olderThan ago,
+// returning how many files were removed and the bytes freed. It holds the cache lock, and tarballs locked by a concurrent
+// save are in use and left alone.
+func PruneImageCache(olderThan time.Duration) (removed int, freed int64, err error) {
+	root := ImageCacheDir()
+	cutoff := time.Now().Add(-olderThan)
+	unlock, err := AcquireCacheLock()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	walkRoot := resolvedDir(root)
+	err = filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == walkRoot && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// removed by another prune
+				return nil
+			}
+			return err
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		rel, err := filepath.Rel(walkRoot, path)
+		if err != nil {
+			return err
+		}
+		// writers lock the path below the unresolved cache dir
+		ok, err := pruneCachedFile(path, filepath.Join(root, rel))
+		if err != nil {
+			return err
+		}
+		if ok {
+			removed++
+			freed += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, freed, fmt.Errorf("prune image cache: %w", err)
+	}
+	return removed, freed, nil
+}
+
+// pruneCachedFile removes a cached file if the artifact it belongs to is not locked, returning whether it was removed
+func pruneCachedFile(path, lockPath string) (bool, error) {
+	ok, err := removeCachedFile(path, lockPath)
+	if ok {
+		klog.Infof("pruned %s from the image cache", path)
+	}
+	return ok, err
+}
+
+// removeCachedFile removes the cached file at path unless a writer holds the lock of the artifact it belongs to,
+// returning whether it was removed. lockPath is path below the unresolved cache dir, which writers lock.
+func removeCachedFile(path, lockPath string) (bool, error) {
+	release, err := lockCachedArtifact(cachedArtifact(lockPath))
+	if err != nil {
+		klog.Infof("skipping %s, it is in use: %v", path, err)
+		return false, nil
+	}
+	defer release()
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// cachedArtifact returns the path of the artifact the cached file at path belongs to: path itself, or the artifact
+// a download temp file or a checksum file is written for
+func cachedArtifact(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".download"):
+		return strings.TrimSuffix(path, ".download")
+	case strings.HasSuffix(path, checksumSuffix):
+		return strings.TrimSuffix(path, checksumSuffix)
+	case strings.HasSuffix(path, ".tmp"):
+		// image.SaveToDir writes a tarball to a temp file with a random suffix, like pause_3.9.1234.tmp
+		base := strings.TrimSuffix(path, ".tmp")
+		if i := strings.LastIndex(base, "."); i > len(filepath.Dir(base)) {
+			return base[:i]
+		}
+	}
+	return path
+}
+
+// lockCachedArtifact takes the locks writers hold while writing the cached artifact, failing quickly if one is held.
+// The ISO and image cache writers lock the artifact path, the kic downloader locks it with a ".lock" suffix.
+func lockCachedArtifact(artifact string) (release func(), err error) {
+	var releasers []mutex.Releaser
+	release = func() {
+		for _, r := range releasers {
+			r.Release()
+		}
+	}
+	for _, name := range []string{artifact, artifact + ".lock"} {
+		spec := lock.PathMutexSpec(name)
+		spec.Delay = 10 * time.Millisecond
+		spec.Timeout = 50 * time.Millisecond
+		r, err := mutex.Acquire(spec)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releasers = append(releasers, r)
+	}
+	return release, nil
+}
+
+// ClearCache removes the given caches for every architecture. It refuses to remove a cache that does not resolve to
+// a path inside the minikube home, or the cache root it links to, so that a misconfigured MINIKUBE_CACHE_DIR
+// cannot delete unrelated files. Artifacts another minikube is writing are left alone and reported in the error.
+func ClearCache(kinds ...CacheKind) error {
+	var dirs []string
+	for _, kind := range kinds {
+		switch kind {
+		case CacheAll:
+			for _, k := range cacheKinds {
+				dirs = append(dirs, filepath.Join(CacheDir(), string(k)))
+			}
+		case CacheImages, CacheKIC, CacheISO:
+			dirs = append(dirs, filepath.Join(CacheDir(), string(kind)))
+		default:
+			return fmt.Errorf("unknown cache kind %q", kind)
+		}
+	}
+
+	home, err := filepath.EvalSymlinks(localpath.MiniPath())
+	if err != nil {
+		return fmt.Errorf("resolve minikube home: %w", err)
+	}
+	// a cache root moved by RelocateCache is linked from the minikube home
+	relocated := resolvedDir(localpath.MakeMiniPath("cache"))
+	unlock, err := AcquireCacheLock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var inUse []string
+	for _, dir := range dirs {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("resolve cache dir: %w", err)
+		}
+		if !strictlyUnderDir(resolved, home) && !strictlyUnderDir(resolved, relocated) {
+			return fmt.Errorf("refusing to clear %s: it is outside of the minikube home %s", resolved, home)
+		}
+		klog.Infof("clearing cache %s", resolved)
+		busy, err := clearCacheDir(resolved, dir)
+		if err != nil {
+			return fmt.Errorf("clear cache: %w", err)
+		}
+		inUse = append(inUse, busy...)
+	}
+	if len(inUse) > 0 {
+		return fmt.Errorf("unable to clear %s: in use by another minikube, try again once it completes", strings.Join(inUse, ", "))
+	}
+	return nil
+}
+
+// clearCacheDir removes the files below the resolved cache dir and the directories left empty, returning the files
+// skipped because their artifact is being written. dir is the unresolved cache dir, below which writers lock artifacts.
+func clearCacheDir(resolved, dir string) (inUse []string, err error) {
+	err = filepath.WalkDir(resolved, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(resolved, path)
+		if err != nil {
+			return err
+		}
+		ok, err := removeCachedFile(path, filepath.Join(dir, rel))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if _, err := os.Lstat(path); err == nil {
+				inUse = append(inUse, path)
+			}
+		}
+		return nil
+	})
+	removeEmptyDirs(resolved)
+	return inUse, err
+}
+
+// resolvedDir returns dir with symlinks followed, since filepath.WalkDir does not descend into a root that is a link,
+// or dir itself if it cannot be resolved
+func resolvedDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return dir
+}
+
+// strictlyUnderDir returns true if path is inside of dir and is not dir itself
+func strictlyUnderDir(path, dir string) bool {
+	return underDir(path, dir) && filepath.Clean(path) != filepath.Clean(dir)
+}
+
+// archDirs are the architecture subdirectories of the image cache, which also holds legacy images directly
+var archDirs = map[string]bool{"386": true, "amd64": true, "arm": true, "arm64": true, "ppc64le": true, "riscv64": true, "s390x": true}
+
+// MigrateLegacyCache moves images cached by a minikube that did not keep a subdirectory per architecture into the one of
+// the current architecture, returning how many were moved. Images already present there are left alone,
+// so it is safe to run repeatedly.
+func MigrateLegacyCache() (moved int, err error) {
+	root := filepath.Join(CacheDir(), string(CacheImages))
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read image cache: %w", err)
+	}
+
+	unlock, err := AcquireCacheLock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	dst := ImageCacheDir()
+	for _, e := range entries {
+		if archDirs[e.Name()] {
+			continue
+		}
+		legacy := filepath.Join(root, e.Name())
+		err := filepath.WalkDir(legacy, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dst, rel)
+			if _, err := os.Stat(target); err == nil {
+				klog.Infof("skipping migration of %s, it is already cached at %s", path, target)
+				return nil
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(path, target); err != nil {
+				return err
+			}
+			moved++
+			return nil
+		})
+		if err != nil {
+			return moved, fmt.Errorf("migrate %s: %w", legacy, err)
+		}
+		removeEmptyDirs(legacy)
+	}
+	return moved, nil
+}
+
+// removeEmptyDirs removes dir and the directories below it that are left empty
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, e.Name()))
+		}
+	}
+	// fails for directories that are not empty
+	_ = os.Remove(dir)
+}
+
+// ListCachedImages returns the references of the images in ImageCacheDir, sorted. The cache stores an image at its
+// reference with ":" replaced by "_", so a tag that itself contains "_" cannot be told apart and is split at the last one.
+func ListCachedImages() ([]string, error) {
+	root := resolvedDir(ImageCacheDir())
+	var images []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == profilesCacheDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !isCachedImageFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		images = append(images, cachedImageRef(filepath.ToSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list cached images: %w", err)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// isCachedImageFile returns false for the files kept next to the image tarballs, like checksums and partial writes
+func isCachedImageFile(name string) bool {
+	for _, suffix := range []string{checksumSuffix, ".tmp", ".lock", ".download"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return !strings.HasPrefix(name, ".")
+}
+
+// cachedImageRef reconstructs the image reference of the tarball at rel, a slash separated path below the image cache
+func cachedImageRef(rel string) string {
+	i := strings.LastIndex(rel, "/") + 1
+	dir, name := rel[:i], rel[i:]
+	digest := ""
+	if at := strings.Index(name, "@"); at >= 0 {
+		name, digest = name[:at], strings.Replace(name[at:], "_", ":", 1)
+	}
+	if j := strings.LastIndex(name, "_"); j > 0 {
+		name = name[:j] + ":" + name[j+1:]
+	}
+	// the port of a registry host was sanitized too, like localhost:5000 to localhost_5000
+	if host, rest, found := strings.Cut(dir, "/"); found {
+		if j := strings.LastIndex(host, "_"); j > 0 && isDigits(host[j+1:]) && (strings.Contains(host[:j], ".") || host[:j] == "localhost") {
+			dir = host[:j] + ":" + host[j+1:] + "/" + rest
+		}
+	}
+	return dir + name + digest
+}
+
+// isDigits returns true if s is a non-empty string of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// symlinkDir links link to the directory target. It is a variable so that it can fall back to a junction on Windows,
+// where creating symlinks requires a privilege.
+var symlinkDir = func(target, link string) error {
+	err := os.Symlink(target, link)
+	if err != nil && runtime.GOOS == "windows" {
+		if _, jerr := runCommand("cmd", "/c", "mklink", "/J", link, target); jerr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// RelocateCache moves the contents of CacheDir to target, which must be an absolute path to a missing or empty directory
+// outside of the cache, and replaces CacheDir with a symlink to it. It must not run while other minikube processes use the cache.
+func RelocateCache(target string) error {
+	if !filepath.IsAbs(target) {
+		return fmt.Errorf("cache target %q is not an absolute path", target)
+	}
+	src := CacheDir()
+	if resolvedDir(src) == resolvedDir(target) {
+		klog.Infof("cache %s already is at %s", src, target)
+		return nil
+	}
+	if underDir(resolvedDir(target), resolvedDir(src)) || underDir(target, src) {
+		return fmt.Errorf("cache target %s is inside of the cache %s", target, src)
+	}
+	if entries, err := os.ReadDir(target); err == nil && len(entries) > 0 {
+		return fmt.Errorf("cache target %s is not empty", target)
+	}
+
+	if st, err := os.Lstat(src); err == nil && st.IsDir() {
+		if err := moveDir(src, target); err != nil {
+			return fmt.Errorf("move cache to %s: %w", target, err)
+		}
+	} else if err == nil {
+		// a symlink to a previous location, whose contents stay there
+		return fmt.Errorf("cache %s is not a directory, it may already be relocated", src)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("create cache target: %w", err)
+		}
+	} else {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		return err
+	}
+	if err := symlinkDir(target, src); err != nil {
+		return fmt.Errorf("link cache to %s: %w", target, err)
+	}
+	klog.Infof("relocated cache %s to %s", src, target)
+	return nil
+}
+
+// moveDir moves the directory src to dst, copying it when they are on different filesystems
+func moveDir(src, dst string) error {
+	// an empty target would make rename fail
+	_ = os.Remove(dst)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyDir(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyDir recursively copies the directory src to dst, preserving permissions, modification times and symlinks.
+// Where dst does not support symlinks, the files they point to are copied instead.
+func copyDir(src, dst string) error {
+	symlinks := true
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(to, info.Mode().Perm()); err != nil {
+				return err
+			}
+			if path == src {
+				symlinks = SupportsSymlinks(dst)
+			}
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			if !symlinks {
+				return copyLinkTarget(path, to)
+			}
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, to)
+		case d.Type().IsRegular():
+			if err := copyFile(path, to, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chtimes(to, info.ModTime(), info.ModTime())
+		}
+		return nil
+	})
+}
+
+// copyLinkTarget copies the regular file the symlink link points to into dst
+func copyLinkTarget(link, dst string) error {
+	info, err := os.Stat(link)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s links to a %s, which cannot be copied without symlinks", link, info.Mode().Type())
+	}
+	if err := copyFile(link, dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// copyFile copies the regular file src to dst
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
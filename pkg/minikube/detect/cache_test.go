@@ -0,0 +1,717 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/juju/mutex"
+	"github.com/spf13/viper"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+func TestCacheDirOverride(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("MINIKUBE_HOME", home)
+	defaultCache := filepath.Join(home, ".minikube", "cache")
+
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv(constants.MinikubeCacheDirEnv, "")
+		if got := CacheDir(); got != defaultCache {
+			t.Errorf("CacheDir() = %q, want %q", got, defaultCache)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		shared := filepath.Join(t.TempDir(), "shared")
+		t.Setenv(constants.MinikubeCacheDirEnv, shared)
+		if got := CacheDir(); got != shared {
+			t.Errorf("CacheDir() = %q, want %q", got, shared)
+		}
+		if got, want := ImageCacheDir(), filepath.Join(shared, "images", runtime.GOARCH); got != want {
+			t.Errorf("ImageCacheDir() = %q, want %q", got, want)
+		}
+		if got, want := ISOCacheDir(), filepath.Join(shared, "iso", runtime.GOARCH); got != want {
+			t.Errorf("ISOCacheDir() = %q, want %q", got, want)
+		}
+		if _, err := os.Stat(shared); err != nil {
+			t.Errorf("override was not created: %v", err)
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		t.Setenv(constants.MinikubeCacheDirEnv, "")
+		shared := t.TempDir()
+		viper.Set(cacheDirConfigKey, shared)
+		defer viper.Set(cacheDirConfigKey, "")
+		if got, want := KICCacheDir(), filepath.Join(shared, "kic", runtime.GOARCH); got != want {
+			t.Errorf("KICCacheDir() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("relative", func(t *testing.T) {
+		t.Setenv(constants.MinikubeCacheDirEnv, "cache")
+		if got := CacheDir(); got != defaultCache {
+			t.Errorf("CacheDir() = %q, want the default %q for a relative override", got, defaultCache)
+		}
+	})
+}
+
+func TestValidateCacheDir(t *testing.T) {
+	if err := ValidateCacheDir(t.TempDir()); err != nil {
+		t.Errorf("ValidateCacheDir() = %v, want nil for a writable dir", err)
+	}
+	if err := ValidateCacheDir("relative/cache"); err == nil {
+		t.Error("ValidateCacheDir() = nil, want an error for a relative path")
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCacheDir(file); err == nil {
+		t.Error("ValidateCacheDir() = nil, want an error for a file")
+	}
+
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		readOnly := t.TempDir()
+		if err := os.Chmod(readOnly, 0555); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(readOnly, 0755)
+		if err := ValidateCacheDir(readOnly); err == nil {
+			t.Error("ValidateCacheDir() = nil, want an error for a read-only dir")
+		}
+	}
+}
+
+// writeCacheFile creates a file of the given size below the cache dir
+func writeCacheFile(t *testing.T, path string, size int) {
+	t.Helper()
+	p := filepath.Join(CacheDir(), path)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheDirSize(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	size, err := CacheDirSize()
+	if err != nil || size != 0 {
+		t.Fatalf("CacheDirSize() = %d, %v, want 0 for an empty cache", size, err)
+	}
+
+	writeCacheFile(t, "images/amd64/registry.k8s.io/pause_3.9", 1000)
+	writeCacheFile(t, "images/arm64/registry.k8s.io/pause_3.9", 500)
+	writeCacheFile(t, "kic/amd64/kicbase_v0.0.42.tar", 4096)
+	writeCacheFile(t, "iso/amd64/minikube-v1.32.0-amd64.iso", 2048)
+	// only the image, kic and ISO caches are counted
+	writeCacheFile(t, "preloaded-tarball/preloaded-images.tar.lz4", 100000)
+
+	size, err = CacheDirSize()
+	if err != nil {
+		t.Fatalf("CacheDirSize() returned an error: %v", err)
+	}
+	if want := int64(1000 + 500 + 4096 + 2048); size != want {
+		t.Errorf("CacheDirSize() = %d, want %d", size, want)
+	}
+
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		locked := filepath.Join(CacheDir(), "images", "amd64", "registry.k8s.io")
+		if err := os.Chmod(locked, 0000); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(locked, 0755)
+		size, err = CacheDirSize()
+		if err != nil {
+			t.Fatalf("CacheDirSize() returned an error for an unreadable dir: %v", err)
+		}
+		if want := int64(500 + 4096 + 2048); size != want {
+			t.Errorf("CacheDirSize() = %d, want %d skipping the unreadable dir", size, want)
+		}
+	}
+}
+
+func TestPruneImageCache(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	removed, freed, err := PruneImageCache(time.Hour)
+	if err != nil || removed != 0 || freed != 0 {
+		t.Fatalf("PruneImageCache() = %d, %d, %v, want nothing pruned from a missing cache", removed, freed, err)
+	}
+
+	images := filepath.Join("images", runtime.GOARCH, "registry.k8s.io")
+	fixtures := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"pause_3.9", 100, 48 * time.Hour},
+		{"etcd_3.5.9-0", 2000, 30 * time.Hour},
+		{"coredns/coredns_v1.10.1", 300, 10 * time.Hour},
+		{"kube-apiserver_v1.28.3", 4000, time.Minute},
+		{"locked_v1", 50, 72 * time.Hour},
+	}
+	for _, f := range fixtures {
+		writeCacheFile(t, filepath.Join(images, f.name), f.size)
+		mtime := time.Now().Add(-f.age)
+		if err := os.Chtimes(filepath.Join(CacheDir(), images, f.name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a tarball being written by image.SaveToDir
+	releaser, err := mutex.Acquire(lock.PathMutexSpec(filepath.Join(CacheDir(), images, "locked_v1")))
+	if err != nil {
+		t.Fatalf("failed to lock fixture: %v", err)
+	}
+	defer releaser.Release()
+
+	removed, freed, err = PruneImageCache(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PruneImageCache() returned an error: %v", err)
+	}
+	if removed != 2 || freed != 2100 {
+		t.Errorf("PruneImageCache() = %d, %d, want 2 files and 2100 bytes", removed, freed)
+	}
+
+	for _, f := range fixtures {
+		_, err := os.Stat(filepath.Join(CacheDir(), images, f.name))
+		if kept := err == nil; kept != (f.age < 24*time.Hour || f.name == "locked_v1") {
+			t.Errorf("%s kept = %v after pruning", f.name, kept)
+		}
+	}
+}
+
+func TestPruneImageCacheConcurrently(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+	old := time.Now().Add(-48 * time.Hour)
+	for i := 0; i < 20; i++ {
+		name := filepath.Join("images", runtime.GOARCH, "image_"+strconv.Itoa(i))
+		writeCacheFile(t, name, 10)
+		if err := os.Chtimes(filepath.Join(CacheDir(), name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := make(chan int, 2)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			removed, _, err := PruneImageCache(time.Hour)
+			results <- removed
+			errs <- err
+		}()
+	}
+	total := 0
+	for i := 0; i < 2; i++ {
+		total += <-results
+		if err := <-errs; err != nil {
+			t.Errorf("PruneImageCache() returned an error: %v", err)
+		}
+	}
+	// the cache lock serializes the prunes, so each file is removed exactly once
+	if total != 20 {
+		t.Errorf("pruned %d files in total, want 20", total)
+	}
+}
+
+func TestCacheDirForProfile(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	got, err := ImageCacheDirForProfile("test-profile")
+	if err != nil {
+		t.Fatalf("ImageCacheDirForProfile() returned an error: %v", err)
+	}
+	if want := filepath.Join(ImageCacheDir(), "_profiles", "test-profile"); got != want {
+		t.Errorf("ImageCacheDirForProfile() = %q, want %q", got, want)
+	}
+	if got, _ := KICCacheDirForProfile("p2.example"); got != filepath.Join(KICCacheDir(), "_profiles", "p2.example") {
+		t.Errorf("KICCacheDirForProfile() = %q, want it under %q", got, KICCacheDir())
+	}
+	if got, _ := ISOCacheDirForProfile(constants.DefaultClusterName); got != ISOCacheDir() {
+		t.Errorf("ISOCacheDirForProfile() = %q, want the global %q for the default profile", got, ISOCacheDir())
+	}
+
+	for _, profile := range []string{"", ".", "..", "../other", "a/b", `a\b`, "c:", "/abs"} {
+		if dir, err := ImageCacheDirForProfile(profile); err == nil {
+			t.Errorf("ImageCacheDirForProfile(%q) = %q, want an error", profile, dir)
+		}
+	}
+}
+
+func TestCacheDirForArch(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	for _, arch := range []string{"amd64", "arm64", "arm"} {
+		t.Run(arch, func(t *testing.T) {
+			for kind, dirForArch := range map[string]func(string) (string, error){
+				"images": ImageCacheDirForArch,
+				"kic":    KICCacheDirForArch,
+				"iso":    ISOCacheDirForArch,
+			} {
+				got, err := dirForArch(arch)
+				if err != nil {
+					t.Fatalf("%s cache dir for %s returned an error: %v", kind, arch, err)
+				}
+				if want := filepath.Join(CacheDir(), kind, arch); got != want {
+					t.Errorf("%s cache dir for %s = %q, want %q", kind, arch, got, want)
+				}
+			}
+		})
+	}
+
+	if got, _ := ImageCacheDirForArch(runtime.GOARCH); validateArch(runtime.GOARCH) == nil && got != ImageCacheDir() {
+		t.Errorf("ImageCacheDirForArch(%q) = %q, want ImageCacheDir() %q", runtime.GOARCH, got, ImageCacheDir())
+	}
+
+	for _, arch := range []string{"", "mips64", "../amd64"} {
+		if _, err := ISOCacheDirForArch(arch); !errors.Is(err, ErrUnsupportedArch) {
+			t.Errorf("ISOCacheDirForArch(%q) error = %v, want ErrUnsupportedArch", arch, err)
+		}
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("MINIKUBE_HOME", home)
+	t.Setenv(constants.MinikubeCacheDirEnv, "")
+
+	populate := func(t *testing.T) {
+		t.Helper()
+		writeCacheFile(t, "images/amd64/registry.k8s.io/pause_3.9", 10)
+		writeCacheFile(t, "images/arm64/registry.k8s.io/pause_3.9", 10)
+		writeCacheFile(t, "kic/amd64/kicbase_v0.0.42.tar", 10)
+		writeCacheFile(t, "iso/amd64/minikube-v1.32.0-amd64.iso", 10)
+		writeCacheFile(t, "preloaded-tarball/preloaded-images.tar.lz4", 10)
+	}
+	exists := func(kind string) bool {
+		_, err := os.Stat(filepath.Join(CacheDir(), kind))
+		return err == nil
+	}
+
+	t.Run("selective", func(t *testing.T) {
+		populate(t)
+		if err := ClearCache(CacheImages, CacheISO); err != nil {
+			t.Fatalf("ClearCache() returned an error: %v", err)
+		}
+		for kind, want := range map[string]bool{"images": false, "iso": false, "kic": true, "preloaded-tarball": true} {
+			if got := exists(kind); got != want {
+				t.Errorf("%s cache exists = %v, want %v", kind, got, want)
+			}
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		populate(t)
+		if err := ClearCache(CacheAll); err != nil {
+			t.Fatalf("ClearCache() returned an error: %v", err)
+		}
+		for kind, want := range map[string]bool{"images": false, "iso": false, "kic": false, "preloaded-tarball": true} {
+			if got := exists(kind); got != want {
+				t.Errorf("%s cache exists = %v, want %v", kind, got, want)
+			}
+		}
+		// clearing missing caches is not an error
+		if err := ClearCache(CacheAll); err != nil {
+			t.Errorf("ClearCache() of missing caches returned an error: %v", err)
+		}
+	})
+
+	t.Run("in use", func(t *testing.T) {
+		populate(t)
+		writeCacheFile(t, "iso/amd64/minikube-v1.33.0-amd64.iso.download", 10)
+		writeCacheFile(t, "kic/amd64/kicbase_v0.0.43.tar", 10)
+		// download.ISO locks the ISO it downloads, download.ImageToCache the kic tarball with a ".lock" suffix
+		var releasers []mutex.Releaser
+		for _, name := range []string{"iso/amd64/minikube-v1.33.0-amd64.iso", "kic/amd64/kicbase_v0.0.43.tar.lock"} {
+			releaser, err := mutex.Acquire(lock.PathMutexSpec(filepath.Join(CacheDir(), name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			releasers = append(releasers, releaser)
+		}
+
+		err := ClearCache(CacheAll)
+		if err == nil || !strings.Contains(err.Error(), "minikube-v1.33.0-amd64.iso.download") || !strings.Contains(err.Error(), "kicbase_v0.0.43.tar") {
+			t.Errorf("ClearCache() = %v, want an error naming the artifacts being written", err)
+		}
+		for path, want := range map[string]bool{
+			"iso/amd64/minikube-v1.33.0-amd64.iso.download": true,
+			"kic/amd64/kicbase_v0.0.43.tar":                 true,
+			"iso/amd64/minikube-v1.32.0-amd64.iso":          false,
+			"kic/amd64/kicbase_v0.0.42.tar":                 false,
+			"images":                                        false,
+		} {
+			_, err := os.Stat(filepath.Join(CacheDir(), path))
+			if got := err == nil; got != want {
+				t.Errorf("%s exists = %v after ClearCache(), want %v", path, got, want)
+			}
+		}
+
+		for _, r := range releasers {
+			r.Release()
+		}
+		if err := ClearCache(CacheAll); err != nil {
+			t.Errorf("ClearCache() = %v once the writers completed, want nil", err)
+		}
+	})
+
+	t.Run("outside home", func(t *testing.T) {
+		outside := t.TempDir()
+		t.Setenv(constants.MinikubeCacheDirEnv, outside)
+		populate(t)
+		if err := ClearCache(CacheImages); err == nil {
+			t.Error("ClearCache() = nil, want an error for a cache outside of the minikube home")
+		}
+		if !exists("images") {
+			t.Error("ClearCache() removed a cache outside of the minikube home")
+		}
+	})
+
+	t.Run("linked outside home", func(t *testing.T) {
+		t.Setenv(constants.MinikubeCacheDirEnv, "")
+		outside := t.TempDir()
+		if err := os.WriteFile(filepath.Join(outside, "keep"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(outside, filepath.Join(CacheDir(), "kic")); err != nil {
+			// creating symlinks requires a privilege on windows
+			t.Skipf("symlinks are not available: %v", err)
+		}
+		if err := ClearCache(CacheKIC); err == nil {
+			t.Error("ClearCache() = nil, want an error for a cache linked outside of the minikube home")
+		}
+		if _, err := os.Stat(filepath.Join(outside, "keep")); err != nil {
+			t.Errorf("ClearCache() removed files outside of the minikube home: %v", err)
+		}
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		if err := ClearCache("preloaded-tarball"); err == nil {
+			t.Error("ClearCache() = nil, want an error for an unknown kind")
+		}
+	})
+}
+
+func TestCachedArtifact(t *testing.T) {
+	dir := filepath.Join("cache", "iso", "amd64")
+	tests := map[string]string{
+		filepath.Join(dir, "minikube-v1.32.0-amd64.iso"):          filepath.Join(dir, "minikube-v1.32.0-amd64.iso"),
+		filepath.Join(dir, "minikube-v1.32.0-amd64.iso.download"): filepath.Join(dir, "minikube-v1.32.0-amd64.iso"),
+		filepath.Join(dir, "minikube-v1.32.0-amd64.iso.sha256"):   filepath.Join(dir, "minikube-v1.32.0-amd64.iso"),
+		filepath.Join(dir, "pause_3.9.1234567.tmp"):               filepath.Join(dir, "pause_3.9"),
+		filepath.Join(dir, "odd.tmp"):                             filepath.Join(dir, "odd.tmp"),
+	}
+	for path, want := range tests {
+		if got := cachedArtifact(path); got != want {
+			t.Errorf("cachedArtifact(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestAcquireCacheLock(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	var holders, maxHolders int32
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := AcquireCacheLock()
+			if err != nil {
+				errs <- err
+				return
+			}
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				m := atomic.LoadInt32(&maxHolders)
+				if n <= m || atomic.CompareAndSwapInt32(&maxHolders, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("AcquireCacheLock() returned an error: %v", err)
+	}
+	if maxHolders != 1 {
+		t.Errorf("the cache lock was held by %d writers at once, want 1", maxHolders)
+	}
+}
+
+func TestAcquireCacheLockTimeout(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+	orig := cacheLockTimeout
+	cacheLockTimeout = 50 * time.Millisecond
+	defer func() { cacheLockTimeout = orig }()
+
+	unlock, err := AcquireCacheLock()
+	if err != nil {
+		t.Fatalf("AcquireCacheLock() returned an error: %v", err)
+	}
+	if _, err := AcquireCacheLock(); err == nil {
+		t.Error("AcquireCacheLock() = nil error, want a timeout while the lock is held")
+	}
+	if _, _, err := PruneImageCache(0); err == nil {
+		t.Error("PruneImageCache() = nil error, want a timeout while the lock is held")
+	}
+
+	unlock()
+	unlock, err = AcquireCacheLock()
+	if err != nil {
+		t.Fatalf("AcquireCacheLock() returned an error after unlocking: %v", err)
+	}
+	unlock()
+}
+
+func TestMigrateLegacyCache(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	if moved, err := MigrateLegacyCache(); err != nil || moved != 0 {
+		t.Fatalf("MigrateLegacyCache() = %d, %v, want 0 for a missing cache", moved, err)
+	}
+
+	arch := runtime.GOARCH
+	other := "arm64"
+	if arch == other {
+		other = "amd64"
+	}
+	// legacy images mixed with the per-arch layout
+	writeCacheFile(t, "images/registry.k8s.io/pause_3.9", 1)
+	writeCacheFile(t, "images/gcr.io/k8s-minikube/storage-provisioner_v5", 2)
+	writeCacheFile(t, "images/registry.k8s.io/etcd_3.5.9-0", 3)
+	writeCacheFile(t, filepath.Join("images", arch, "registry.k8s.io/etcd_3.5.9-0"), 4)
+	writeCacheFile(t, filepath.Join("images", other, "registry.k8s.io/pause_3.9"), 5)
+
+	moved, err := MigrateLegacyCache()
+	if err != nil {
+		t.Fatalf("MigrateLegacyCache() returned an error: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("MigrateLegacyCache() moved %d images, want 2", moved)
+	}
+
+	sizes := map[string]int64{
+		filepath.Join(arch, "registry.k8s.io/pause_3.9"):                  1,
+		filepath.Join(arch, "gcr.io/k8s-minikube/storage-provisioner_v5"): 2,
+		filepath.Join(arch, "registry.k8s.io/etcd_3.5.9-0"):               4,
+		filepath.Join(other, "registry.k8s.io/pause_3.9"):                 5,
+		// already cached for the current architecture, so left in place
+		"registry.k8s.io/etcd_3.5.9-0": 3,
+	}
+	for p, size := range sizes {
+		st, err := os.Stat(filepath.Join(CacheDir(), "images", p))
+		if err != nil {
+			t.Errorf("%s is missing after migrating: %v", p, err)
+			continue
+		}
+		if st.Size() != size {
+			t.Errorf("%s has size %d, want %d", p, st.Size(), size)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(CacheDir(), "images", "gcr.io")); err == nil {
+		t.Error("MigrateLegacyCache() left the empty legacy gcr.io directory behind")
+	}
+
+	if moved, err := MigrateLegacyCache(); err != nil || moved != 0 {
+		t.Errorf("MigrateLegacyCache() = %d, %v on a second run, want 0", moved, err)
+	}
+}
+
+func TestListCachedImages(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+
+	images, err := ListCachedImages()
+	if err != nil || len(images) != 0 {
+		t.Fatalf("ListCachedImages() = %v, %v, want no images for a missing cache", images, err)
+	}
+
+	arch := filepath.Join("images", runtime.GOARCH)
+	for _, f := range []string{
+		"registry.k8s.io/pause_3.9",
+		"registry.k8s.io/coredns/coredns_v1.10.1",
+		"gcr.io/k8s-minikube/storage-provisioner_v5",
+		"gcr.io/k8s-minikube/storage-provisioner_v5.sha256",
+		"docker.io/kubernetesui/dashboard_v2.7.0@sha256_2e500d29e9d5f4a086b908eb8dfe7ecac57d2ab09d65b24f588b1d449841ef93",
+		"localhost_5000/my_app_v1",
+		"myregistry.example.com_8443/team/app_v2",
+		"busybox",
+		"registry.k8s.io/etcd_3.5.9-0.2871788108.tmp",
+		"_profiles/test/registry.k8s.io/pause_3.9",
+	} {
+		writeCacheFile(t, filepath.Join(arch, f), 1)
+	}
+	// images of other architectures are not listed
+	writeCacheFile(t, "images/s390x/registry.k8s.io/pause_3.8", 1)
+
+	images, err = ListCachedImages()
+	if err != nil {
+		t.Fatalf("ListCachedImages() returned an error: %v", err)
+	}
+	want := []string{
+		"busybox",
+		"docker.io/kubernetesui/dashboard:v2.7.0@sha256:2e500d29e9d5f4a086b908eb8dfe7ecac57d2ab09d65b24f588b1d449841ef93",
+		"gcr.io/k8s-minikube/storage-provisioner:v5",
+		"localhost:5000/my_app:v1",
+		"myregistry.example.com:8443/team/app:v2",
+		"registry.k8s.io/coredns/coredns:v1.10.1",
+		"registry.k8s.io/pause:3.9",
+	}
+	if strings.Join(images, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ListCachedImages() = %q, want %q", images, want)
+	}
+}
+
+func TestRelocateCache(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	t.Setenv(constants.MinikubeCacheDirEnv, "")
+	src := CacheDir()
+	writeCacheFile(t, filepath.Join("images", runtime.GOARCH, "registry.k8s.io/pause_3.9"), 10)
+	writeCacheFile(t, filepath.Join("iso", runtime.GOARCH, "minikube-v1.32.0-amd64.iso"), 20)
+
+	if err := RelocateCache(filepath.Join(src, "images", "elsewhere")); err == nil {
+		t.Error("RelocateCache() = nil, want an error for a target inside of the cache")
+	}
+	if err := RelocateCache("relative/cache"); err == nil {
+		t.Error("RelocateCache() = nil, want an error for a relative target")
+	}
+	full := t.TempDir()
+	if err := os.WriteFile(filepath.Join(full, "data"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RelocateCache(full); err == nil {
+		t.Error("RelocateCache() = nil, want an error for a target that is not empty")
+	}
+
+	target := filepath.Join(t.TempDir(), "big-disk", "minikube-cache")
+	if err := symlinkDir(t.TempDir(), filepath.Join(t.TempDir(), "probe")); err != nil {
+		t.Skipf("symlinks are not available: %v", err)
+	}
+	if err := RelocateCache(target); err != nil {
+		t.Fatalf("RelocateCache() returned an error: %v", err)
+	}
+
+	if st, err := os.Lstat(src); err != nil || st.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("cache root %s is not a symlink after relocating: %v", src, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "iso", runtime.GOARCH, "minikube-v1.32.0-amd64.iso")); err != nil {
+		t.Errorf("ISO was not moved to the target: %v", err)
+	}
+
+	// the helpers read through the link
+	if size, err := CacheDirSize(); err != nil || size != 30 {
+		t.Errorf("CacheDirSize() = %d, %v after relocating, want 30", size, err)
+	}
+	if images, err := ListCachedImages(); err != nil || len(images) != 1 || images[0] != "registry.k8s.io/pause:3.9" {
+		t.Errorf("ListCachedImages() = %v, %v after relocating, want the pause image", images, err)
+	}
+	if err := RelocateCache(target); err != nil {
+		t.Errorf("RelocateCache() to the current location returned an error: %v", err)
+	}
+	if err := ClearCache(CacheISO); err != nil {
+		t.Errorf("ClearCache() of a relocated cache returned an error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "iso")); err == nil {
+		t.Error("ClearCache() did not clear the relocated ISO cache")
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{"a": "1", "sub/b": "22", "sub/deeper/c": "333"}
+	for p, content := range files {
+		full := filepath.Join(src, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(src, "a"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyDir(src, dst); err != nil {
+		t.Fatalf("copyDir() returned an error: %v", err)
+	}
+	for p, content := range files {
+		b, err := os.ReadFile(filepath.Join(dst, p))
+		if err != nil || string(b) != content {
+			t.Errorf("copied %s = %q, %v, want %q", p, b, err, content)
+		}
+	}
+	// pruning relies on the modification times of cached images
+	st, err := os.Stat(filepath.Join(dst, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.ModTime().Equal(mtime) {
+		t.Errorf("copied modification time = %v, want %v", st.ModTime(), mtime)
+	}
+}
+
+func TestCopyLinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("target", link); err != nil {
+		t.Skipf("symlinks are not supported in %s: %v", dir, err)
+	}
+
+	dst := filepath.Join(dir, "copy")
+	if err := copyLinkTarget(link, dst); err != nil {
+		t.Fatalf("copyLinkTarget() returned an error: %v", err)
+	}
+	st, err := os.Lstat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Mode().IsRegular() {
+		t.Errorf("copyLinkTarget() created a %s, want a regular file", st.Mode().Type())
+	}
+	if b, err := os.ReadFile(dst); err != nil || string(b) != "content" {
+		t.Errorf("copied link target = %q, %v, want %q", b, err, "content")
+	}
+}
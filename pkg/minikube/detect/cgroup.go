@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrNoCPUQuota is returned by CgroupCPUQuota when minikube's CPU usage is not constrained by a cgroup
+var ErrNoCPUQuota = errors.New("no cgroup CPU quota")
+
+// DetectCgroupVersion returns the cgroup hierarchy version mounted on the host: 2 for the unified hierarchy,
+// 1 for the legacy (or hybrid) hierarchy, or 0 if it is unknown or the host is not Linux.
+func DetectCgroupVersion() int {
+	if runtime.GOOS != "linux" {
+		return 0
+	}
+	return cgroupVersion()
+}
+
+func cgroupVersion() int {
+	if fileExists("/sys/fs/cgroup/cgroup.controllers") {
+		return 2
+	}
+	if fileExists("/sys/fs/cgroup/memory") {
+		return 1
+	}
+	return 0
+}
+
+// CgroupCPUQuota returns the number of CPUs, possibly fractional (e.g. 2.5), that the cgroup of the container
+// minikube runs in is allowed to use. It returns ErrNoCPUQuota outside of a container or when no quota is set.
+func CgroupCPUQuota() (float64, error) {
+	if !IsInContainer() {
+		return 0, ErrNoCPUQuota
+	}
+	return cgroupCPUQuota()
+}
+
+func cgroupCPUQuota() (float64, error) {
+	if quota := cgroupCPULimit(); quota > 0 {
+		return quota, nil
+	}
+	return 0, ErrNoCPUQuota
+}
+
+// cgroupCPULimit returns the CPU bandwidth limit of minikube's cgroup as a number of CPUs,
+// or 0 when it is unlimited or cannot be determined
+func cgroupCPULimit() float64 {
+	// cgroup v2: "<quota> <period>", where quota may be "max"
+	if b, err := os.ReadFile(hostPath("/sys/fs/cgroup/cpu.max")); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0
+		}
+		return cpuQuotaRatio(fields[0], fields[1])
+	}
+
+	// cgroup v1: a quota of -1 means unlimited
+	for _, dir := range []string{"/sys/fs/cgroup/cpu", "/sys/fs/cgroup/cpu,cpuacct"} {
+		quota, err := os.ReadFile(hostPath(dir + "/cpu.cfs_quota_us"))
+		if err != nil {
+			continue
+		}
+		period, err := os.ReadFile(hostPath(dir + "/cpu.cfs_period_us"))
+		if err != nil {
+			continue
+		}
+		return cpuQuotaRatio(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+	return 0
+}
+
+// cpuQuotaRatio returns quota/period, or 0 if either is invalid or the quota is not positive
+func cpuQuotaRatio(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// cgroupMemoryLimitMiB returns the memory limit of minikube's cgroup in MiB, or 0 when it is unlimited or cannot be determined
+func cgroupMemoryLimitMiB() int {
+	for _, p := range []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		b, err := os.ReadFile(hostPath(p))
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		// cgroup v2 reports "max" when unlimited, and v1 a number near the maximum int64 rounded to the page size
+		if err != nil || limit <= 0 || limit >= 1<<62 {
+			return 0
+		}
+		return int(limit >> 20)
+	}
+	return 0
+}
+
+// cgroupMemoryUsageMiB returns the memory used by minikube's cgroup in MiB, or 0 if it cannot be determined
+func cgroupMemoryUsageMiB() int {
+	for _, p := range []string{"/sys/fs/cgroup/memory.current", "/sys/fs/cgroup/memory/memory.usage_in_bytes"} {
+		b, err := os.ReadFile(hostPath(p))
+		if err != nil {
+			continue
+		}
+		usage, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		if err != nil || usage < 0 {
+			return 0
+		}
+		return int(usage >> 20)
+	}
+	return 0
+}
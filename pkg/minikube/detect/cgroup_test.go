@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCgroupVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  int
+	}{
+		{"unified", map[string]string{"/sys/fs/cgroup/cgroup.controllers": "cpuset cpu io memory hugetlb pids rdma misc\n"}, 2},
+		{"legacy", map[string]string{"/sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n"}, 1},
+		{"hybrid", map[string]string{
+			"/sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+			"/sys/fs/cgroup/unified/cgroup.controllers":   "",
+		}, 1},
+		{"unknown", map[string]string{}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := cgroupVersion(); got != tc.want {
+				t.Errorf("cgroupVersion() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCgroupCPULimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  float64
+	}{
+		{"v2 limited", map[string]string{"/sys/fs/cgroup/cpu.max": "250000 100000\n"}, 2.5},
+		{"v2 unlimited", map[string]string{"/sys/fs/cgroup/cpu.max": "max 100000\n"}, 0},
+		{"v1 limited", map[string]string{"/sys/fs/cgroup/cpu/cpu.cfs_quota_us": "200000\n", "/sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n"}, 2},
+		{"v1 cpuacct", map[string]string{"/sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us": "50000\n", "/sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n"}, 0.5},
+		{"v1 unlimited", map[string]string{"/sys/fs/cgroup/cpu/cpu.cfs_quota_us": "-1\n", "/sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n"}, 0},
+		{"none", map[string]string{}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := cgroupCPULimit(); got != tc.want {
+				t.Errorf("cgroupCPULimit() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCgroupMemoryLimitMiB(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantLimit int
+		wantUsage int
+	}{
+		{"v2 limited", map[string]string{"/sys/fs/cgroup/memory.max": "2147483648\n", "/sys/fs/cgroup/memory.current": "536870912\n"}, 2048, 512},
+		{"v2 unlimited", map[string]string{"/sys/fs/cgroup/memory.max": "max\n", "/sys/fs/cgroup/memory.current": "536870912\n"}, 0, 512},
+		{"v1 limited", map[string]string{"/sys/fs/cgroup/memory/memory.limit_in_bytes": "1073741824\n", "/sys/fs/cgroup/memory/memory.usage_in_bytes": "268435456\n"}, 1024, 256},
+		{"v1 unlimited", map[string]string{"/sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n"}, 0, 0},
+		{"none", map[string]string{}, 0, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := cgroupMemoryLimitMiB(); got != tc.wantLimit {
+				t.Errorf("cgroupMemoryLimitMiB() = %d, want %d", got, tc.wantLimit)
+			}
+			if got := cgroupMemoryUsageMiB(); got != tc.wantUsage {
+				t.Errorf("cgroupMemoryUsageMiB() = %d, want %d", got, tc.wantUsage)
+			}
+		})
+	}
+}
+
+func TestCgroupCPUQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  float64
+	}{
+		{"v2 limited", map[string]string{"/sys/fs/cgroup/cpu.max": "150000 100000\n"}, 1.5},
+		{"v2 max", map[string]string{"/sys/fs/cgroup/cpu.max": "max 100000\n"}, 0},
+		{"v1 limited", map[string]string{"/sys/fs/cgroup/cpu/cpu.cfs_quota_us": "400000\n", "/sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n"}, 4},
+		{"v1 unlimited", map[string]string{"/sys/fs/cgroup/cpu/cpu.cfs_quota_us": "-1\n", "/sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n"}, 0},
+		{"no cgroup", map[string]string{}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			got, err := cgroupCPUQuota()
+			if tc.want == 0 {
+				if !errors.Is(err, ErrNoCPUQuota) {
+					t.Errorf("cgroupCPUQuota() error = %v, want ErrNoCPUQuota", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cgroupCPUQuota() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("cgroupCPUQuota() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCgroupCPUQuotaOutsideContainer(t *testing.T) {
+	t.Setenv("container", "")
+	fakeHostRoot(t, map[string]string{"/sys/fs/cgroup/cpu.max": "150000 100000\n"})
+	if _, err := CgroupCPUQuota(); !errors.Is(err, ErrNoCPUQuota) {
+		t.Errorf("CgroupCPUQuota() error = %v, want ErrNoCPUQuota outside a container", err)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// checksumSuffix is appended to the path of a cached artifact to name the file holding its SHA-256 checksum
+const checksumSuffix = ".sha256"
+
+// ComputeSHA256 returns the hex encoded SHA-256 checksum of the file at path
+func ComputeSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("checksum %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteArtifactChecksum stores the checksum of the cached artifact at path next to it, in the format of sha256sum,
+// for VerifyCachedArtifact to detect an artifact corrupted after it was written. A second line records the size and
+// modification time of the artifact, which VerifyCachedArtifact compares to skip hashing an unchanged artifact.
+func WriteArtifactChecksum(path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	sum, err := ComputeSHA256(path)
+	if err != nil {
+		return err
+	}
+	return writeArtifactChecksum(path, sum, st)
+}
+
+func writeArtifactChecksum(path, sum string, st fs.FileInfo) error {
+	b := fmt.Sprintf("%s  %s\n%d %d\n", sum, filepath.Base(path), st.Size(), st.ModTime().UnixNano())
+	return lock.WriteFile(path+checksumSuffix, []byte(b), 0644)
+}
+
+// VerifyCachedArtifact returns true if the SHA-256 checksum of the file at path matches expectedSHA256.
+// When expectedSHA256 is empty the checksum stored by WriteArtifactChecksum is expected instead. An artifact without
+// a checksum file is reported as corrupted: writers store the checksum only once the artifact is complete, so a
+// missing one means the write was interrupted, or that an older minikube cached it and it is downloaded once more.
+// To keep cache hits cheap, the artifact is only hashed when its size or modification time differ from the ones
+// recorded with the checksum. Corruption that preserves both, such as bit rot on disk, is therefore not detected;
+// pass expectedSHA256 to hash the artifact unconditionally.
+// Callers should treat false as a cache miss and download the artifact again.
+func VerifyCachedArtifact(path, expectedSHA256 string) (bool, error) {
+	if expectedSHA256 != "" {
+		sum, err := ComputeSHA256(path)
+		if err != nil {
+			return false, err
+		}
+		return strings.EqualFold(sum, strings.TrimSpace(expectedSHA256)), nil
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	b, err := os.ReadFile(path + checksumSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read checksum: %w", err)
+	}
+	lines := strings.SplitN(string(b), "\n", 3)
+	fields := strings.Fields(lines[0])
+	if len(fields) == 0 {
+		return false, nil
+	}
+	if len(lines) > 1 && lines[1] == fmt.Sprintf("%d %d", st.Size(), st.ModTime().UnixNano()) {
+		return true, nil
+	}
+
+	sum, err := ComputeSHA256(path)
+	if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(sum, fields[0]) {
+		return false, nil
+	}
+	// the artifact was touched but not changed, record its new modification time to skip hashing it next time
+	if err := writeArtifactChecksum(path, sum, st); err != nil {
+		klog.Warningf("failed to store the checksum of %s: %v", path, err)
+	}
+	return true, nil
+}
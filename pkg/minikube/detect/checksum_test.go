@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeSHA256(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"The quick brown fox jumps over the lazy dog", "d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592"},
+	}
+
+	for _, tc := range tests {
+		p := filepath.Join(t.TempDir(), "artifact")
+		if err := os.WriteFile(p, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ComputeSHA256(p)
+		if err != nil {
+			t.Fatalf("ComputeSHA256() returned an error: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("ComputeSHA256(%q) = %s, want %s", tc.content, got, tc.want)
+		}
+	}
+
+	if _, err := ComputeSHA256(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ComputeSHA256() = nil error, want an error for a missing file")
+	}
+}
+
+func TestVerifyCachedArtifact(t *testing.T) {
+	const abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	p := filepath.Join(t.TempDir(), "minikube-v1.32.0-amd64.iso")
+	if err := os.WriteFile(p, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for expected, want := range map[string]bool{abc: true, "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD": true, "0000": false} {
+		if got, err := VerifyCachedArtifact(p, expected); err != nil || got != want {
+			t.Errorf("VerifyCachedArtifact(%s) = %v, %v, want %v", expected, got, err, want)
+		}
+	}
+
+	// no stored checksum, as left by an interrupted write
+	if ok, err := VerifyCachedArtifact(p, ""); err != nil || ok {
+		t.Errorf("VerifyCachedArtifact() = %v, %v, want false for an artifact without a checksum file", ok, err)
+	}
+	if _, err := os.Stat(p + ".sha256"); !os.IsNotExist(err) {
+		t.Errorf("VerifyCachedArtifact() stored a checksum for an artifact without one: %v", err)
+	}
+
+	if err := WriteArtifactChecksum(p); err != nil {
+		t.Fatalf("WriteArtifactChecksum() returned an error: %v", err)
+	}
+	b, err := os.ReadFile(p + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := abc + "  minikube-v1.32.0-amd64.iso\n"; !strings.HasPrefix(string(b), want) {
+		t.Errorf("checksum file = %q, want it to start with %q", b, want)
+	}
+	if ok, err := VerifyCachedArtifact(p, ""); err != nil || !ok {
+		t.Errorf("VerifyCachedArtifact() = %v, %v, want true for an intact artifact", ok, err)
+	}
+
+	// touched without being changed
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(p, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyCachedArtifact(p, ""); err != nil || !ok {
+		t.Errorf("VerifyCachedArtifact() = %v, %v, want true for a touched artifact", ok, err)
+	}
+
+	// an artifact of the recorded size and modification time is not hashed again, so corruption preserving both
+	// goes unnoticed unless the expected checksum is passed
+	if err := os.WriteFile(p, []byte("xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyCachedArtifact(p, ""); err != nil || !ok {
+		t.Errorf("VerifyCachedArtifact() = %v, %v, want true for an artifact matching the recorded size and time", ok, err)
+	}
+	if ok, err := VerifyCachedArtifact(p, abc); err != nil || ok {
+		t.Errorf("VerifyCachedArtifact(%s) = %v, %v, want false for an artifact corrupted in place", abc, ok, err)
+	}
+
+	// corrupted after it was cached, as by an interrupted write
+	if err := os.WriteFile(p, []byte("ab"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyCachedArtifact(p, ""); err != nil || ok {
+		t.Errorf("VerifyCachedArtifact() = %v, %v, want false for a corrupted artifact", ok, err)
+	}
+
+	// rewritten with the same size
+	if err := os.WriteFile(p, []byte("xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyCachedArtifact(p, ""); err != nil || ok {
+		t.Errorf("VerifyCachedArtifact() = %v, %v, want false for an artifact rewritten with the same size", ok, err)
+	}
+
+	if _, err := VerifyCachedArtifact(filepath.Join(t.TempDir(), "missing"), abc); err == nil {
+		t.Error("VerifyCachedArtifact() = nil error, want an error for a missing artifact")
+	}
+}
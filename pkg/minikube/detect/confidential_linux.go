@@ -0,0 +1,34 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+// HasSEV returns true if the host can run AMD SEV confidential guests
+func HasSEV() bool {
+	if kvmModuleParamEnabled("kvm_amd", "sev") {
+		return true
+	}
+	return fileExists("/dev/sev")
+}
+
+// HasTDX returns true if the host can run Intel TDX confidential guests
+func HasTDX() bool {
+	if kvmModuleParamEnabled("kvm_intel", "tdx") {
+		return true
+	}
+	// set by kernels that initialized the TDX module
+	return cpuinfoHasFlag(readCPUInfo(), "tdx_host_platform")
+}
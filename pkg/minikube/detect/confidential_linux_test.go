@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+func TestConfidentialComputing(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		sev   bool
+		tdx   bool
+	}{
+		{"none", map[string]string{"/sys/module/kvm_amd/parameters/sev": "N\n"}, false, false},
+		{"sev param", map[string]string{"/sys/module/kvm_amd/parameters/sev": "Y\n"}, true, false},
+		{"sev numeric param", map[string]string{"/sys/module/kvm_amd/parameters/sev": "1\n"}, true, false},
+		{"sev device", map[string]string{"/dev/sev": ""}, true, false},
+		{"tdx param", map[string]string{"/sys/module/kvm_intel/parameters/tdx": "Y\n"}, false, true},
+		{"tdx cpu flag", map[string]string{"/proc/cpuinfo": "processor\t: 0\nflags\t\t: fpu vme vmx tdx_host_platform\n"}, false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := HasSEV(); got != tc.sev {
+				t.Errorf("HasSEV() = %t, want %t", got, tc.sev)
+			}
+			if got := HasTDX(); got != tc.tdx {
+				t.Errorf("HasTDX() = %t, want %t", got, tc.tdx)
+			}
+		})
+	}
+}
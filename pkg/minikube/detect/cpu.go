@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/cpuid"
+	gopscpu "github.com/shirou/gopsutil/v3/cpu"
+)
+
+// isX86 returns true if minikube was built for an x86 architecture, where CPUID is available
+func isX86() bool {
+	return runtime.GOARCH == "amd64" || runtime.GOARCH == "386"
+}
+
+// HasAVX2 returns true if the host CPU and OS support AVX2 instructions
+func HasAVX2() bool {
+	return isX86() && cpuid.CPU.AVX2()
+}
+
+// HasAVX512 returns true if the host CPU and OS support the AVX-512 foundation instructions
+func HasAVX512() bool {
+	return isX86() && cpuid.CPU.AVX512F()
+}
+
+// HasSSE42 returns true if the host CPU supports SSE4.2 instructions
+func HasSSE42() bool {
+	return isX86() && cpuid.CPU.SSE42()
+}
+
+// CPUBrandName returns the brand name reported by the host CPU, e.g. "Intel(R) Core(TM) i7-10710U CPU @ 1.10GHz",
+// or "unknown" if the CPU does not report one, as is the case on most ARM hosts
+func CPUBrandName() string {
+	if b := strings.TrimSpace(cpuid.CPU.BrandName); b != "" {
+		return b
+	}
+	return "unknown"
+}
+
+// CPUVendor returns the normalized vendor of the host CPU, such as "Intel", "AMD" or "Apple", or "unknown"
+func CPUVendor() string {
+	return normalizeCPUVendor(cpuid.CPU.VendorID, cpuid.CPU.BrandName, runtime.GOOS, runtime.GOARCH)
+}
+
+func normalizeCPUVendor(vendor cpuid.Vendor, brand, goos, goarch string) string {
+	switch vendor {
+	case cpuid.Intel:
+		return "Intel"
+	case cpuid.AMD:
+		return "AMD"
+	case cpuid.VIA:
+		return "VIA"
+	case cpuid.Transmeta:
+		return "Transmeta"
+	case cpuid.NSC:
+		return "National Semiconductor"
+	}
+
+	// hypervisors may replace the vendor id, but usually pass the brand name through
+	switch {
+	case strings.HasPrefix(brand, "VirtualApple"), strings.HasPrefix(brand, "Apple"):
+		return "Apple"
+	case strings.Contains(brand, "Intel"):
+		return "Intel"
+	case strings.Contains(brand, "AMD"):
+		return "AMD"
+	}
+
+	if goos == "darwin" && goarch == "arm64" {
+		return "Apple"
+	}
+	return "unknown"
+}
+
+// LogicalCPUCount returns the number of CPUs minikube may use. When running inside a container
+// it is capped by the container's cgroup CPU quota, so that guests are not given more CPUs than are available.
+func LogicalCPUCount() int {
+	n := runtime.NumCPU()
+	quota, err := CgroupCPUQuota()
+	if err != nil {
+		return n
+	}
+	return capCPUCount(n, quota)
+}
+
+// capCPUCount caps n to a CPU quota, rounding down but never below one CPU. A quota of 0 means unlimited.
+func capCPUCount(n int, quota float64) int {
+	if quota <= 0 {
+		return n
+	}
+	limit := int(quota)
+	if limit < 1 {
+		limit = 1
+	}
+	if limit < n {
+		return limit
+	}
+	return n
+}
+
+// PhysicalCoreCount returns the number of physical CPU cores of the host, not counting hyperthreads.
+// It falls back to the logical CPU count when the topology cannot be determined.
+func PhysicalCoreCount() int {
+	if runtime.GOOS == "linux" {
+		// unlike CPUID, the topology of the kernel covers every socket and ARM hosts
+		if n := sysfsPhysicalCores(); n > 0 {
+			return n
+		}
+		if n := cpuinfoPhysicalCores(readCPUInfo()); n > 0 {
+			return n
+		}
+		return runtime.NumCPU()
+	}
+	if n, err := gopscpu.Counts(false); err == nil && n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
+
+// appleSiliconRe matches the generation in brand strings such as "Apple M1 Pro"
+var appleSiliconRe = regexp.MustCompile(`^Apple (M\d+)\b`)
+
+// AppleSiliconGeneration returns the Apple Silicon generation of the host, such as "M1" or "M3",
+// or "" when not running natively on an Apple Silicon Mac
+func AppleSiliconGeneration() string {
+	if runtime.GOOS != "darwin" || runtime.GOARCH != "arm64" {
+		return ""
+	}
+	return appleSiliconGeneration()
+}
+
+func appleSiliconGeneration() string {
+	o, err := runCommand("sysctl", "-n", "machdep.cpu.brand_string")
+	if err != nil {
+		return ""
+	}
+	return parseAppleSiliconGeneration(string(o))
+}
+
+// parseAppleSiliconGeneration extracts the generation from a machdep.cpu.brand_string value
+func parseAppleSiliconGeneration(brand string) string {
+	m := appleSiliconRe.FindStringSubmatch(strings.TrimSpace(brand))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"testing"
+
+	"github.com/klauspost/cpuid"
+	"golang.org/x/sys/cpu"
+)
+
+func TestCPUFeatures(t *testing.T) {
+	features := []struct {
+		name   string
+		detect func() bool
+		x86    bool
+	}{
+		{"AVX2", HasAVX2, cpu.X86.HasAVX2},
+		{"AVX512", HasAVX512, cpu.X86.HasAVX512F},
+		{"SSE4.2", HasSSE42, cpu.X86.HasSSE42},
+	}
+
+	for _, f := range features {
+		t.Run(f.name, func(t *testing.T) {
+			got := f.detect()
+			if !isX86() {
+				if got {
+					t.Errorf("Has%s() = true on a non-x86 architecture", f.name)
+				}
+				return
+			}
+			// cross check against the independent detection in golang.org/x/sys/cpu
+			if got != f.x86 {
+				t.Errorf("Has%s() = %t, but golang.org/x/sys/cpu reports %t", f.name, got, f.x86)
+			}
+		})
+	}
+}
+
+func TestNormalizeCPUVendor(t *testing.T) {
+	tests := []struct {
+		name   string
+		vendor cpuid.Vendor
+		brand  string
+		goos   string
+		goarch string
+		want   string
+	}{
+		{"intel", cpuid.Intel, "Intel(R) Core(TM) i7-10710U CPU @ 1.10GHz", "linux", "amd64", "Intel"},
+		{"amd", cpuid.AMD, "AMD EPYC 7B12", "linux", "amd64", "AMD"},
+		{"via", cpuid.VIA, "VIA Nano", "linux", "386", "VIA"},
+		{"rosetta", cpuid.Other, "VirtualApple @ 2.50GHz processor", "darwin", "amd64", "Apple"},
+		{"apple silicon", cpuid.Other, "", "darwin", "arm64", "Apple"},
+		{"hyper-v guest", cpuid.MSVM, "Intel(R) Xeon(R) Platinum 8272CL CPU @ 2.60GHz", "windows", "amd64", "Intel"},
+		{"arm64 linux", cpuid.Other, "", "linux", "arm64", "unknown"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := normalizeCPUVendor(tc.vendor, tc.brand, tc.goos, tc.goarch); got != tc.want {
+				t.Errorf("normalizeCPUVendor() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCPUBrandName(t *testing.T) {
+	if CPUBrandName() == "" {
+		t.Errorf("CPUBrandName() returned an empty string, want a brand name or \"unknown\"")
+	}
+}
+
+func TestCapCPUCount(t *testing.T) {
+	tests := []struct {
+		n     int
+		quota float64
+		want  int
+	}{
+		{8, 0, 8},
+		{8, 2.5, 2},
+		{8, 0.5, 1},
+		{2, 4, 2},
+	}
+
+	for _, tc := range tests {
+		if got := capCPUCount(tc.n, tc.quota); got != tc.want {
+			t.Errorf("capCPUCount(%d, %v) = %d, want %d", tc.n, tc.quota, got, tc.want)
+		}
+	}
+	if LogicalCPUCount() < 1 || PhysicalCoreCount() < 1 {
+		t.Errorf("LogicalCPUCount() = %d, PhysicalCoreCount() = %d; want at least 1", LogicalCPUCount(), PhysicalCoreCount())
+	}
+}
+
+func TestAppleSiliconGeneration(t *testing.T) {
+	tests := []struct {
+		brand string
+		want  string
+	}{
+		{"Apple M1\n", "M1"},
+		{"Apple M1 Pro\n", "M1"},
+		{"Apple M2 Max\n", "M2"},
+		{"Apple M3\n", "M3"},
+		{"Apple M10 Ultra\n", "M10"},
+		{"VirtualApple @ 2.50GHz processor\n", ""},
+		{"Intel(R) Core(TM) i9-9980HK CPU @ 2.40GHz\n", ""},
+		{"Apple Mx\n", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.brand, func(t *testing.T) {
+			fakeCommand(t, map[string]string{"sysctl -n machdep.cpu.brand_string": tc.brand})
+			if got := appleSiliconGeneration(); got != tc.want {
+				t.Errorf("appleSiliconGeneration() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
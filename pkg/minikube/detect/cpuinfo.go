@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readCPUInfo returns the contents of /proc/cpuinfo, or "" if it cannot be read
+func readCPUInfo() string {
+	b, err := os.ReadFile(hostPath("/proc/cpuinfo"))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// cpuinfoField returns the value of the first "key : value" line of cpuinfo matching key
+func cpuinfoField(cpuinfo, key string) string {
+	for _, line := range strings.Split(cpuinfo, "\n") {
+		k, v, found := strings.Cut(line, ":")
+		if found && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// cpuinfoHasFlag returns true if the CPU flags (x86) or features (arm) listed in cpuinfo include flag
+func cpuinfoHasFlag(cpuinfo, flag string) bool {
+	flags := cpuinfoField(cpuinfo, "flags")
+	if flags == "" {
+		flags = cpuinfoField(cpuinfo, "Features")
+	}
+	for _, f := range strings.Fields(flags) {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// cpuinfoARMVersion returns the ARM architecture version (5, 6, 7, 8...) described by cpuinfo, or 0 if unknown
+func cpuinfoARMVersion(cpuinfo string) int {
+	// the model name is more accurate than "CPU architecture", which ARM11 cores report as 7
+	model := cpuinfoField(cpuinfo, "model name")
+	if model == "" {
+		model = cpuinfoField(cpuinfo, "Processor")
+	}
+	for _, v := range []int{5, 6, 7, 8} {
+		if strings.Contains(model, "(v"+strconv.Itoa(v)+"l)") || strings.HasPrefix(model, "ARMv"+strconv.Itoa(v)) {
+			return v
+		}
+	}
+
+	arch := cpuinfoField(cpuinfo, "CPU architecture")
+	if arch == "AArch64" {
+		return 8
+	}
+	end := 0
+	for end < len(arch) && arch[end] >= '0' && arch[end] <= '9' {
+		end++
+	}
+	v, err := strconv.Atoi(arch[:end])
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// armVariant returns the 32-bit ARM platform variant minikube images are selected by for an ARM architecture version.
+// ARMv8 CPUs running a 32-bit userland, i.e. a 64-bit kernel with a 32-bit minikube, can only use 32-bit (v7) images.
+func armVariant(version int) string {
+	switch {
+	case version <= 5:
+		return "arm/v5"
+	case version == 6:
+		return "arm/v6"
+	default:
+		// "arm" (== "arm/v7")
+		return "arm"
+	}
+}
+
+// sysfsPhysicalCores counts the distinct physical cores in the CPU topology the kernel exports in sysfs,
+// which ARM kernels fill in too. It returns 0 if the topology is unavailable.
+func sysfsPhysicalCores() int {
+	dirs, err := filepath.Glob(hostPath("/sys/devices/system/cpu/cpu[0-9]*/topology"))
+	if err != nil {
+		return 0
+	}
+	cores := map[string]bool{}
+	for _, dir := range dirs {
+		pkg, err := os.ReadFile(filepath.Join(dir, "physical_package_id"))
+		if err != nil {
+			return 0
+		}
+		core, err := os.ReadFile(filepath.Join(dir, "core_id"))
+		if err != nil {
+			return 0
+		}
+		cores[strings.TrimSpace(string(pkg))+"/"+strings.TrimSpace(string(core))] = true
+	}
+	return len(cores)
+}
+
+// cpuinfoPhysicalCores counts the distinct physical cores listed in cpuinfo. Kernels that do not report
+// the core topology, as is common on ARM, list one entry per core, so every processor is counted then.
+func cpuinfoPhysicalCores(cpuinfo string) int {
+	cores := map[string]bool{}
+	processors := 0
+	for _, block := range strings.Split(cpuinfo, "\n\n") {
+		if cpuinfoField(block, "processor") == "" {
+			continue
+		}
+		processors++
+		if core := cpuinfoField(block, "core id"); core != "" {
+			cores[cpuinfoField(block, "physical id")+"/"+core] = true
+		}
+	}
+	if len(cores) > 0 {
+		return len(cores)
+	}
+	return processors
+}
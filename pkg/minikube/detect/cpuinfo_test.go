@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCPUInfoARMVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		cpuinfo string
+		version int
+		variant string
+	}{
+		{"armv5", "Processor\t: Feroceon 88FR131 rev 1 (v5l)\nBogoMIPS\t: 1192.75\nCPU architecture: 5TE\n", 5, "arm/v5"},
+		{"raspberry pi zero", "processor\t: 0\nmodel name\t: ARMv6-compatible processor rev 7 (v6l)\nCPU architecture: 7\n", 6, "arm/v6"},
+		{"raspberry pi 3 32-bit", "processor\t: 0\nmodel name\t: ARMv7 Processor rev 4 (v7l)\nCPU architecture: 7\n", 7, "arm"},
+		{"64-bit kernel 32-bit userland", "processor\t: 0\nBogoMIPS\t: 108.00\nFeatures\t: fp asimd evtstrm crc32 cpuid\nCPU implementer\t: 0x41\nCPU architecture: 8\n", 8, "arm"},
+		{"old aarch64 kernel", "Processor\t: AArch64 Processor rev 4 (aarch64)\nCPU architecture: AArch64\n", 8, "arm"},
+		{"x86", x86CPUInfo, 0, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := cpuinfoARMVersion(tc.cpuinfo)
+			if v != tc.version {
+				t.Fatalf("cpuinfoARMVersion() = %d, want %d", v, tc.version)
+			}
+			if v == 0 {
+				return
+			}
+			if got := armVariant(v); got != tc.variant {
+				t.Errorf("armVariant(%d) = %q, want %q", v, got, tc.variant)
+			}
+		})
+	}
+}
+
+func TestCPUInfoPhysicalCores(t *testing.T) {
+	// two sockets, two cores each, with hyperthreading
+	var x86 strings.Builder
+	processor := 0
+	for socket := 0; socket < 2; socket++ {
+		for thread := 0; thread < 2; thread++ {
+			for core := 0; core < 2; core++ {
+				fmt.Fprintf(&x86, "processor\t: %d\nvendor_id\t: GenuineIntel\nphysical id\t: %d\ncore id\t\t: %d\n\n", processor, socket, core)
+				processor++
+			}
+		}
+	}
+	arm := "processor\t: 0\nBogoMIPS\t: 108.00\nCPU implementer\t: 0x41\n\n" +
+		"processor\t: 1\nBogoMIPS\t: 108.00\nCPU implementer\t: 0x41\n\n" +
+		"processor\t: 2\nBogoMIPS\t: 108.00\nCPU implementer\t: 0x41\n\n" +
+		"processor\t: 3\nBogoMIPS\t: 108.00\nCPU implementer\t: 0x41\n\nHardware\t: BCM2835\n"
+	// arm64 kernels report no topology in cpuinfo either, e.g. on AWS Graviton2
+	var arm64 strings.Builder
+	for processor := 0; processor < 8; processor++ {
+		fmt.Fprintf(&arm64, "processor\t: %d\nBogoMIPS\t: 243.75\nFeatures\t: fp asimd evtstrm aes pmull sha1 sha2 crc32 atomics\nCPU implementer\t: 0x41\nCPU architecture: 8\nCPU part\t: 0xd0c\n\n", processor)
+	}
+
+	tests := []struct {
+		name    string
+		cpuinfo string
+		want    int
+	}{
+		{"x86 hyperthreaded", x86.String(), 4},
+		{"raspberry pi 4", arm, 4},
+		{"arm64", arm64.String(), 8},
+		{"empty", "", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cpuinfoPhysicalCores(tc.cpuinfo); got != tc.want {
+				t.Errorf("cpuinfoPhysicalCores() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSysfsPhysicalCores(t *testing.T) {
+	// topology writes the sysfs topology of logical CPUs, each given as its package and core id
+	topology := func(cpus ...[2]int) map[string]string {
+		files := map[string]string{}
+		for i, c := range cpus {
+			dir := fmt.Sprintf("/sys/devices/system/cpu/cpu%d/topology/", i)
+			files[dir+"physical_package_id"] = fmt.Sprintf("%d\n", c[0])
+			files[dir+"core_id"] = fmt.Sprintf("%d\n", c[1])
+		}
+		return files
+	}
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  int
+	}{
+		// core ids restart on each socket, which CPUID based counting misses
+		{"two sockets with hyperthreading", topology([2]int{0, 0}, [2]int{0, 1}, [2]int{1, 0}, [2]int{1, 1}, [2]int{0, 0}, [2]int{0, 1}, [2]int{1, 0}, [2]int{1, 1}), 4},
+		{"arm64 with SMT", topology([2]int{0, 0}, [2]int{0, 0}, [2]int{0, 1}, [2]int{0, 1}), 2},
+		{"arm64", topology([2]int{0, 0}, [2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}), 4},
+		{"no topology", map[string]string{"/sys/devices/system/cpu/cpu0/online": "1\n"}, 0},
+		{"incomplete topology", map[string]string{"/sys/devices/system/cpu/cpu0/topology/core_id": "0\n"}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := sysfsPhysicalCores(); got != tc.want {
+				t.Errorf("sysfsPhysicalCores() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
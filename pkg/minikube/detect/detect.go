@@ -17,21 +17,66 @@ limitations under the License.
 package detect
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/klauspost/cpuid"
 	"github.com/spf13/viper"
 	"golang.org/x/sys/cpu"
 	"k8s.io/klog/v2"
-	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/constants"
 )
 
+// hostRoot is the root of the filesystem inspected by the file based detectors.
+// It is a variable so that tests can point it at a fixture tree.
+var hostRoot = "/"
+
+// hostPath returns the path of the given absolute host path below hostRoot
+func hostPath(path string) string {
+	return filepath.Join(hostRoot, path)
+}
+
+// fileExists returns true if the given host path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(hostPath(path))
+	return err == nil
+}
+
+// runCommandContext runs the named program and returns its standard output, killing it when ctx is done.
+// It is a variable so that tests can stub the output of external tools.
+var runCommandContext = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, arg...).Output()
+}
+
+// runCommand runs the named program and returns its standard output
+func runCommand(name string, arg ...string) ([]byte, error) {
+	return runCommandContext(context.Background(), name, arg...)
+}
+
+// runCommandTimeout is runCommandContext, killing the command after timeout
+func runCommandTimeout(ctx context.Context, timeout time.Duration, name string, arg ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	o, err := runCommandContext(ctx, name, arg...)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("%s did not complete: %w", name, ctx.Err())
+	}
+	return o, err
+}
+
 // RuntimeOS returns the runtime operating system
 func RuntimeOS() string {
 	return runtime.GOOS
@@ -39,8 +84,15 @@ func RuntimeOS() string {
 
 // RuntimeArch returns the runtime architecture
 func RuntimeArch() string {
+	if arch, ok := forcedArch(); ok {
+		return arch
+	}
 	arch := runtime.GOARCH
 	if arch == "arm" {
+		// prefer what the kernel reports, the cpu flags under-report on some boards
+		if v := cpuinfoARMVersion(readCPUInfo()); v != 0 {
+			return armVariant(v)
+		}
 		// runtime.GOARM
 		if !cpu.ARM.HasVFP {
 			return "arm/v5"
@@ -60,14 +112,750 @@ func IsMicrosoftWSL() bool {
 	return os.Getenv("WSL_DISTRO_NAME") != "" || os.Getenv("WSLPATH") != ""
 }
 
+// WSLDistroName returns the name of the WSL distribution the process is running in, or "" when not in WSL
+func WSLDistroName() string {
+	return os.Getenv("WSL_DISTRO_NAME")
+}
+
+// linkLocalMetadataURL is the base URL of the link-local instance metadata service shared by most clouds.
+// It is a variable so that tests can point it at a local server.
+var linkLocalMetadataURL = "http://169.254.169.254"
+
+// gceMetadataURL is the URL of the GCE metadata server
+var gceMetadataURL = "http://metadata.google.internal"
+
+// equinixMetadataURL is the URL of the Equinix Metal metadata service, which is served over HTTPS
+var equinixMetadataURL = "https://metadata.platformequinix.com/metadata"
+
+// linkLocalMetadataTimeout bounds connecting to and reading from the link-local metadata services
+// so that hosts without a route to 169.254.169.254 are not held up
+var linkLocalMetadataTimeout = 300 * time.Millisecond
+
+// gceMetadataTimeout is the default deadline for IsOnGCE
+const gceMetadataTimeout = 2 * time.Second
+
+// equinixMetadataTimeout bounds name resolution, the TLS handshake and reading the Equinix Metal metadata
+const equinixMetadataTimeout = time.Second
+
+const (
+	// awsMetadataPath is the path of the EC2 instance metadata service
+	awsMetadataPath = "/latest"
+	// azureMetadataPath is the path of the Azure instance metadata service
+	azureMetadataPath = "/metadata/instance?api-version=2021-02-01"
+	// digitalOceanMetadataPath is the path of the DigitalOcean droplet metadata service
+	digitalOceanMetadataPath = "/metadata/v1"
+	// ociMetadataPath is the path of the Oracle Cloud Infrastructure instance metadata service (IMDSv2)
+	ociMetadataPath = "/opc/v2/instance/"
+	// openStackMetadataPath is the path of the OpenStack metadata service
+	openStackMetadataPath = "/openstack/latest/meta_data.json"
+	// vultrMetadataPath is the path of the Vultr metadata service
+	vultrMetadataPath = "/v1.json"
+	// linodeMetadataPath is the path of the Linode metadata service
+	linodeMetadataPath = "/v1"
+	// ibmCloudMetadataVersion is the IBM Cloud metadata API version requested
+	ibmCloudMetadataVersion = "2022-03-01"
+)
+
+// probeMetadata GETs url from a cloud metadata service, giving up after timeout or when ctx is done.
+// Any response other than 200 is returned as an error; otherwise the caller must close the response body.
+func probeMetadata(ctx context.Context, url string, headers map[string]string, timeout time.Duration) (*http.Response, error) {
+	return metadataRequest(ctx, http.MethodGet, url, nil, headers, timeout)
+}
+
+// metadataRequest sends a request to a cloud metadata service, giving up after timeout or when ctx is done.
+// Any response other than 200 is returned as an error; otherwise the caller must close the response body.
+func metadataRequest(ctx context.Context, method, url string, body io.Reader, headers map[string]string, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	c := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// metadata services must never be reached through a proxy
+			Proxy:       nil,
+			DialContext: (&net.Dialer{Timeout: timeout}).DialContext,
+		},
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: unexpected status %d", method, url, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// readMetadata reads a small plain text metadata value from resp and closes its body
+func readMetadata(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("reading metadata: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// decodeMetadata decodes a JSON metadata document from resp into v and closes its body
+func decodeMetadata(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("decoding metadata: %w", err)
+	}
+	return nil
+}
+
+// Detector is a yes/no probe of the host that may block on the network or on external commands.
+// Detect gives up and returns ctx's error when ctx is done.
+type Detector interface {
+	Detect(ctx context.Context) (bool, error)
+}
+
+// DetectorFunc adapts a function to the Detector interface
+type DetectorFunc func(ctx context.Context) (bool, error)
+
+// Detect calls f(ctx)
+func (f DetectorFunc) Detect(ctx context.Context) (bool, error) {
+	return f(ctx)
+}
+
+// background adapts a context-aware probe to memoizedProbe, for the context-less wrappers
+func background(probe DetectorFunc) func() (bool, error) {
+	return func() (bool, error) {
+		return probe(context.Background())
+	}
+}
+
+// memoizedProbe is the detect cache entry of a probe whose outcome does not change while minikube runs
+type memoizedProbe struct {
+	name string
+}
+
+// get returns the cached outcome of probe, running it first if needed
+func (m memoizedProbe) get(probe func() (bool, error)) (bool, error) {
+	return cachedBool(m.name, 0, probe)
+}
+
+// reset discards the cached outcome so that the next get runs the probe again
+func (m memoizedProbe) reset() {
+	forgetDetection(m.name)
+}
+
+var (
+	gceProbe          = memoizedProbe{"gce"}
+	awsProbe          = memoizedProbe{"aws"}
+	azureProbe        = memoizedProbe{"azure"}
+	digitalOceanProbe = memoizedProbe{"digitalocean"}
+	ociProbe          = memoizedProbe{"oci"}
+	ibmCloudProbe     = memoizedProbe{"ibmcloud"}
+	openStackProbe    = memoizedProbe{"openstack"}
+	equinixProbe      = memoizedProbe{"equinixmetal"}
+	vultrProbe        = memoizedProbe{"vultr"}
+	linodeProbe       = memoizedProbe{"linode"}
+)
+
+// ResetCloudDetection discards all cached cloud detection results, for use in tests.
+func ResetCloudDetection() {
+	for _, m := range []memoizedProbe{gceProbe, awsProbe, azureProbe, digitalOceanProbe, ociProbe, ibmCloudProbe, openStackProbe, equinixProbe, vultrProbe, linodeProbe} {
+		m.reset()
+	}
+	forgetDetection(cloudProviderCacheKey)
+
+	awsTokenMu.Lock()
+	awsToken, awsTokenExpiry = "", time.Time{}
+	awsTokenMu.Unlock()
+}
+
+// containerMarkers are strings found in cgroup and mount paths of processes running inside a container
+var containerMarkers = []string{"docker", "containerd", "kubepods", "libpod"}
+
+// IsInContainer returns true if minikube itself is running inside a container, e.g. docker-in-docker.
+func IsInContainer() bool {
+	if in, ok := forcedContainer(); ok {
+		return in
+	}
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return inContainer()
+}
+
+func inContainer() bool {
+	// set by systemd-nspawn, podman and LXC
+	if os.Getenv("container") != "" {
+		return true
+	}
+	if fileExists("/.dockerenv") || fileExists("/run/.containerenv") {
+		return true
+	}
+
+	// cgroup v1 places container processes in a cgroup named after the runtime
+	if b, err := os.ReadFile(hostPath("/proc/1/cgroup")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if containsAny(line, containerMarkers) {
+				return true
+			}
+		}
+	}
+
+	// with cgroup v2 the cgroup path is namespaced, but the root and hostname
+	// mounts still reveal the container runtime's storage
+	if b, err := os.ReadFile(hostPath("/proc/self/mountinfo")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 5 {
+				continue
+			}
+			switch fields[4] {
+			case "/", "/etc/hostname", "/etc/hosts":
+				if containsAny(line, containerMarkers) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// IsInKubernetesPod returns true if minikube is running inside a Kubernetes pod
+func IsInKubernetesPod() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" || fileExists("/var/run/secrets/kubernetes.io/serviceaccount/token")
+}
+
+// containsAny returns true if s contains any of substrs
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsOnGCE determines whether minikube is currently running on GCE.
+// The metadata server is only queried on the first call.
 func IsOnGCE() bool {
-	resp, err := http.Get("http://metadata.google.internal")
+	onGCE, _ := gceProbe.get(func() (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), gceMetadataTimeout)
+		defer cancel()
+		onGCE, err := isOnGCE(ctx)
+		if err != nil {
+			klog.Infof("GCE metadata server not reachable: %v", err)
+		}
+		return onGCE, err
+	})
+	return onGCE
+}
+
+// IsOnGCEWithContext determines whether minikube is currently running on GCE, giving up when ctx is done.
+func IsOnGCEWithContext(ctx context.Context) bool {
+	onGCE, _ := isOnGCE(ctx)
+	return onGCE
+}
+
+func isOnGCE(ctx context.Context) (bool, error) {
+	resp, err := metadataRequest(ctx, http.MethodGet, gceMetadataURL, nil, nil, gceMetadataTimeout)
 	if err != nil {
-		return false
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	return resp.Header.Get("Metadata-Flavor") == "Google", nil
+}
+
+// GCEZone returns the zone of the GCE instance minikube is running on, e.g. "us-central1-a".
+func GCEZone() (string, error) {
+	if !IsOnGCE() {
+		return "", errors.New("not running on GCE")
+	}
+	resp, err := probeMetadata(context.Background(), gceMetadataURL+"/computeMetadata/v1/instance/zone", map[string]string{"Metadata-Flavor": "Google"}, gceMetadataTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to query GCE metadata: %w", err)
+	}
+	zone, err := readMetadata(resp)
+	if err != nil {
+		return "", err
+	}
+
+	return parseGCEZone(zone)
+}
+
+// GCERegion returns the region of the GCE instance minikube is running on, e.g. "us-central1".
+func GCERegion() (string, error) {
+	zone, err := GCEZone()
+	if err != nil {
+		return "", err
+	}
+	return gceRegionFromZone(zone)
+}
+
+// parseGCEZone extracts the zone name from the metadata value "projects/<number>/zones/<zone>"
+func parseGCEZone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	zone := s[strings.LastIndex(s, "/")+1:]
+	if zone == "" {
+		return "", fmt.Errorf("unexpected GCE zone %q", s)
+	}
+	return zone, nil
+}
+
+// gceRegionFromZone derives the region from a zone by dropping the zone suffix, e.g. "us-central1-a" -> "us-central1"
+func gceRegionFromZone(zone string) (string, error) {
+	i := strings.LastIndex(zone, "-")
+	if i <= 0 {
+		return "", fmt.Errorf("unexpected GCE zone %q", zone)
+	}
+	return zone[:i], nil
+}
+
+// IsOnAWS determines whether minikube is currently running on AWS EC2.
+func IsOnAWS() bool {
+	on, _ := awsProbe.get(background(isOnAWS))
+	return on
+}
+
+func isOnAWS(ctx context.Context) (bool, error) {
+	id, err := awsMetadata(ctx, "instance-id")
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(id, "i-"), nil
+}
+
+// AWSInstanceType returns the EC2 instance type minikube is running on, e.g. "t3.micro".
+func AWSInstanceType() (string, error) {
+	if !IsOnAWS() {
+		return "", errors.New("not running on AWS")
+	}
+	return awsMetadata(context.Background(), "instance-type")
+}
+
+// AWSRegion returns the AWS region of the EC2 instance minikube is running on, e.g. "us-east-1".
+func AWSRegion() (string, error) {
+	if !IsOnAWS() {
+		return "", errors.New("not running on AWS")
+	}
+	return awsMetadata(context.Background(), "placement/region")
+}
+
+// awsTokenTTL is the lifetime requested for IMDSv2 session tokens
+const awsTokenTTL = 6 * time.Hour
+
+var (
+	awsTokenMu     sync.Mutex
+	awsToken       string
+	awsTokenExpiry time.Time
+)
+
+// awsSessionToken returns an IMDSv2 session token, reusing a previously issued one while it is valid.
+// An empty token with a nil error means the service did not issue one and IMDSv1 should be used.
+func awsSessionToken(ctx context.Context) (string, error) {
+	awsTokenMu.Lock()
+	defer awsTokenMu.Unlock()
+
+	if awsToken != "" && time.Now().Before(awsTokenExpiry) {
+		return awsToken, nil
+	}
+
+	requested := time.Now()
+	headers := map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": strconv.Itoa(int(awsTokenTTL.Seconds()))}
+	resp, err := metadataRequest(ctx, http.MethodPut, linkLocalMetadataURL+awsMetadataPath+"/api/token", nil, headers, linkLocalMetadataTimeout)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// the metadata service is unreachable, so IMDSv1 would not answer either
+			return "", err
+		}
+		return "", nil
+	}
+	token, err := readMetadata(resp)
+	if err != nil {
+		return "", nil
+	}
+	awsToken = token
+	// leave some margin so that a token is never used right as it expires
+	awsTokenExpiry = requested.Add(awsTokenTTL - time.Minute)
+	return awsToken, nil
+}
+
+// awsMetadata returns the value of the given path under the EC2 instance metadata "meta-data" tree
+func awsMetadata(ctx context.Context, path string) (string, error) {
+	token, err := awsSessionToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("EC2 metadata service not reachable: %w", err)
+	}
+
+	headers := map[string]string{}
+	if token != "" {
+		headers["X-aws-ec2-metadata-token"] = token
+	}
+	resp, err := probeMetadata(ctx, linkLocalMetadataURL+awsMetadataPath+"/meta-data/"+path, headers, linkLocalMetadataTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to query EC2 metadata: %w", err)
+	}
+	return readMetadata(resp)
+}
+
+// IsOnAzure determines whether minikube is currently running on an Azure VM.
+func IsOnAzure() bool {
+	on, _ := azureProbe.get(background(isOnAzure))
+	return on
+}
+
+func isOnAzure(ctx context.Context) (bool, error) {
+	resp, err := probeMetadata(ctx, linkLocalMetadataURL+azureMetadataPath, map[string]string{"Metadata": "true"}, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	var instance struct {
+		Compute struct {
+			AzEnvironment string `json:"azEnvironment"`
+		} `json:"compute"`
+	}
+	if err := decodeMetadata(resp, &instance); err != nil {
+		return false, err
+	}
+
+	return instance.Compute.AzEnvironment != "", nil
+}
+
+// IsOnDigitalOcean determines whether minikube is currently running on a DigitalOcean droplet.
+func IsOnDigitalOcean() bool {
+	on, _ := digitalOceanProbe.get(background(isOnDigitalOcean))
+	return on
+}
+
+func isOnDigitalOcean(ctx context.Context) (bool, error) {
+	resp, err := probeMetadata(ctx, linkLocalMetadataURL+digitalOceanMetadataPath+"/id", nil, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
 	}
+	id, err := readMetadata(resp)
+	if err != nil {
+		return false, err
+	}
+
+	// other clouds answer on the same address, but only DigitalOcean serves a numeric droplet id here
+	_, err = strconv.ParseUint(id, 10, 64)
+	return err == nil, nil
+}
+
+// IsOnOCI determines whether minikube is currently running on Oracle Cloud Infrastructure.
+func IsOnOCI() bool {
+	on, _ := ociProbe.get(background(isOnOCI))
+	return on
+}
+
+func isOnOCI(ctx context.Context) (bool, error) {
+	resp, err := probeMetadata(ctx, linkLocalMetadataURL+ociMetadataPath, map[string]string{"Authorization": "Bearer Oracle"}, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	var instance struct {
+		CanonicalRegionName string `json:"canonicalRegionName"`
+	}
+	if err := decodeMetadata(resp, &instance); err != nil {
+		return false, err
+	}
+
+	return instance.CanonicalRegionName != "", nil
+}
+
+// IsOnIBMCloud determines whether minikube is currently running on an IBM Cloud virtual server instance.
+func IsOnIBMCloud() bool {
+	on, _ := ibmCloudProbe.get(background(isOnIBMCloud))
+	return on
+}
+
+func isOnIBMCloud(ctx context.Context) (bool, error) {
+	headers := map[string]string{
+		"Metadata-Flavor": "ibm",
+		"Content-Type":    "application/json",
+		"Accept":          "application/json",
+	}
+	resp, err := metadataRequest(ctx, http.MethodPut, linkLocalMetadataURL+"/instance_identity/v1/token?version="+ibmCloudMetadataVersion, strings.NewReader(`{"expires_in": 300}`), headers, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeMetadata(resp, &token); err != nil {
+		return false, err
+	}
+	if token.AccessToken == "" {
+		return false, nil
+	}
+
+	headers = map[string]string{
+		"Authorization": "Bearer " + token.AccessToken,
+		"Accept":        "application/json",
+	}
+	resp, err = probeMetadata(ctx, linkLocalMetadataURL+"/metadata/v1/instance?version="+ibmCloudMetadataVersion, headers, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+	var instance struct {
+		ID  string `json:"id"`
+		CRN string `json:"crn"`
+	}
+	if err := decodeMetadata(resp, &instance); err != nil {
+		return false, err
+	}
+
+	return instance.ID != "" && strings.HasPrefix(instance.CRN, "crn:"), nil
+}
+
+// IsOnOpenStack determines whether minikube is currently running on an OpenStack instance.
+func IsOnOpenStack() bool {
+	on, _ := openStackProbe.get(background(isOnOpenStack))
+	return on
+}
+
+func isOnOpenStack(ctx context.Context) (bool, error) {
+	resp, err := probeMetadata(ctx, linkLocalMetadataURL+openStackMetadataPath, nil, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	// other clouds may answer on the same address, so rely on the documented OpenStack fields
+	var metadata struct {
+		UUID string `json:"uuid"`
+	}
+	if err := decodeMetadata(resp, &metadata); err != nil {
+		return false, err
+	}
+
+	return metadata.UUID != "", nil
+}
+
+// IsOnEquinixMetal determines whether minikube is currently running on an Equinix Metal (formerly Packet) server.
+func IsOnEquinixMetal() bool {
+	on, _ := equinixProbe.get(background(isOnEquinixMetal))
+	return on
+}
+
+func isOnEquinixMetal(ctx context.Context) (bool, error) {
+	resp, err := probeMetadata(ctx, equinixMetadataURL, nil, equinixMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	var metadata struct {
+		Class string `json:"class"`
+		Plan  string `json:"plan"`
+	}
+	if err := decodeMetadata(resp, &metadata); err != nil {
+		return false, err
+	}
+
+	return metadata.Class != "" && metadata.Plan != "", nil
+}
+
+// IsOnVultr determines whether minikube is currently running on a Vultr instance.
+func IsOnVultr() bool {
+	on, _ := vultrProbe.get(background(isOnVultr))
+	return on
+}
+
+func isOnVultr(ctx context.Context) (bool, error) {
+	resp, err := probeMetadata(ctx, linkLocalMetadataURL+vultrMetadataPath, nil, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	// AWS and others share the address but do not serve this document
+	var metadata struct {
+		InstanceID string `json:"instanceid"`
+	}
+	if err := decodeMetadata(resp, &metadata); err != nil {
+		return false, err
+	}
+
+	return metadata.InstanceID != "", nil
+}
+
+// IsOnLinode determines whether minikube is currently running on a Linode (Akamai) instance.
+func IsOnLinode() bool {
+	on, _ := linodeProbe.get(background(isOnLinode))
+	return on
+}
+
+func isOnLinode(ctx context.Context) (bool, error) {
+	resp, err := metadataRequest(ctx, http.MethodPut, linkLocalMetadataURL+linodeMetadataPath+"/token", nil, map[string]string{"Metadata-Token-Expiry-Seconds": "300"}, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+	token, err := readMetadata(resp)
+	if err != nil {
+		return false, err
+	}
+	if token == "" {
+		return false, nil
+	}
+
+	headers := map[string]string{
+		"Metadata-Token": token,
+		"Accept":         "application/json",
+	}
+	resp, err = probeMetadata(ctx, linkLocalMetadataURL+linodeMetadataPath+"/instance", headers, linkLocalMetadataTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	// unlike AWS, whose token endpoint lives under /latest, Linode answers here with a JSON document
+	var instance struct {
+		ID     int64  `json:"id"`
+		Region string `json:"region"`
+	}
+	if err := decodeMetadata(resp, &instance); err != nil {
+		return false, err
+	}
+
+	return instance.ID != 0 && instance.Region != "", nil
+}
+
+// Provider is a cloud platform minikube can run on
+type Provider string
+
+const (
+	// ProviderNone means no known cloud platform was detected
+	ProviderNone Provider = ""
+	// ProviderGCE is Google Compute Engine
+	ProviderGCE Provider = "gce"
+	// ProviderAWS is Amazon EC2
+	ProviderAWS Provider = "aws"
+	// ProviderAzure is Microsoft Azure
+	ProviderAzure Provider = "azure"
+	// ProviderDigitalOcean is DigitalOcean
+	ProviderDigitalOcean Provider = "digitalocean"
+	// ProviderOCI is Oracle Cloud Infrastructure
+	ProviderOCI Provider = "oci"
+	// ProviderIBMCloud is IBM Cloud
+	ProviderIBMCloud Provider = "ibmcloud"
+	// ProviderOpenStack is OpenStack
+	ProviderOpenStack Provider = "openstack"
+	// ProviderEquinixMetal is Equinix Metal, formerly Packet
+	ProviderEquinixMetal Provider = "equinixmetal"
+	// ProviderVultr is Vultr
+	ProviderVultr Provider = "vultr"
+	// ProviderLinode is Linode, part of Akamai
+	ProviderLinode Provider = "linode"
+)
 
-	return resp.Header.Get("Metadata-Flavor") == "Google"
+// cloudProbe pairs a cloud provider with the detector recognizing it
+type cloudProbe struct {
+	provider Provider
+	detector Detector
+}
+
+// cloudProbes are the cloud detectors consulted by CloudProvider, in order of precedence
+var cloudProbes = []cloudProbe{
+	{ProviderGCE, DetectorFunc(isOnGCE)},
+	{ProviderAWS, DetectorFunc(isOnAWS)},
+	{ProviderAzure, DetectorFunc(isOnAzure)},
+	{ProviderDigitalOcean, DetectorFunc(isOnDigitalOcean)},
+	{ProviderOCI, DetectorFunc(isOnOCI)},
+	{ProviderIBMCloud, DetectorFunc(isOnIBMCloud)},
+	{ProviderOpenStack, DetectorFunc(isOnOpenStack)},
+	{ProviderEquinixMetal, DetectorFunc(isOnEquinixMetal)},
+	{ProviderVultr, DetectorFunc(isOnVultr)},
+	{ProviderLinode, DetectorFunc(isOnLinode)},
+}
+
+// cloudProbeDeadline bounds how long CloudProvider waits for all probes
+var cloudProbeDeadline = 2 * time.Second
+
+// cloudProviderCacheKey is the detect cache entry of CloudProvider
+const cloudProviderCacheKey = "cloud-provider"
+
+// CloudProvider returns the cloud platform minikube is running on, or ProviderNone.
+// All providers are probed in parallel and the result is cached until ResetCloudDetection.
+func CloudProvider() Provider {
+	if p, ok := forcedCloud(); ok {
+		return p
+	}
+	p := cachedString(cloudProviderCacheKey, 0, func() string {
+		p := detectCloudProvider(context.Background())
+		klog.Infof("detected cloud provider: %q", p)
+		return string(p)
+	})
+	return Provider(p)
+}
+
+// CloudProviderWithContext is CloudProvider, giving up on the probes still running when ctx is done.
+// The result is not cached, as a canceled detection may have missed the provider.
+func CloudProviderWithContext(ctx context.Context) Provider {
+	if p, ok := forcedCloud(); ok {
+		return p
+	}
+	return detectCloudProvider(ctx)
+}
+
+func detectCloudProvider(ctx context.Context) Provider {
+	ctx, cancel := context.WithTimeout(ctx, cloudProbeDeadline)
+	defer cancel()
+
+	probes := cloudProbes
+	// buffered so that probes still running after the deadline do not leak blocked goroutines
+	results := make(chan int, len(probes))
+	for i, p := range probes {
+		go func(i int, d Detector) {
+			if ok, _ := d.Detect(ctx); ok {
+				results <- i
+				return
+			}
+			results <- -1
+		}(i, p.detector)
+	}
+
+	matched := make([]bool, len(probes))
+	for range probes {
+		select {
+		case i := <-results:
+			if i >= 0 {
+				matched[i] = true
+			}
+		case <-ctx.Done():
+			klog.Warningf("cloud provider detection did not complete: %v", ctx.Err())
+			return firstMatch(probes, matched)
+		}
+	}
+	return firstMatch(probes, matched)
+}
+
+// bareMetalProviders are the cloud providers that only rent out dedicated servers
+var bareMetalProviders = map[Provider]bool{
+	ProviderEquinixMetal: true,
+}
+
+// IsOnBareMetal reports whether minikube appears to be running on dedicated hardware,
+// i.e. on a bare metal cloud, or no cloud provider was detected and DetectHypervisor found no virtualization.
+// This is a best-effort heuristic built on the cached results of both: it may report true
+// inside a VM whose platform hides itself from the guest.
+func IsOnBareMetal() bool {
+	p := CloudProvider()
+	if bareMetalProviders[p] {
+		return true
+	}
+	return p == ProviderNone && DetectHypervisor() == ""
+}
+
+// firstMatch returns the highest precedence provider whose probe matched
+func firstMatch(probes []cloudProbe, matched []bool) Provider {
+	for i, m := range matched {
+		if m {
+			return probes[i].provider
+		}
+	}
+	return ProviderNone
 }
 
 // IsCloudShell determines whether minikube is running inside CloudShell
@@ -78,21 +866,47 @@ func IsCloudShell() bool {
 
 // IsAmd64M1Emulation  determines whether amd64 minikube binary is running on M1 mac in emulation mode
 func IsAmd64M1Emulation() bool {
-	return runtime.GOARCH == "amd64" && strings.HasPrefix(cpuid.CPU.BrandName, "VirtualApple")
+	return runtime.GOARCH == "amd64" && strings.HasPrefix(CPUBrandName(), "VirtualApple")
 }
 
 // EffectiveArch return architecture to use in minikube VM/container
 // may differ from host arch
 func EffectiveArch() string {
-	if IsAmd64M1Emulation() {
+	if arch, ok := forcedArch(); ok {
+		return arch
+	}
+	if IsAmd64M1Emulation() || IsAmd64WindowsARMEmulation() {
 		return "arm64"
 	}
 	return runtime.GOARCH
 }
 
+// IsRISCV returns true if minikube is running on a 64-bit RISC-V host
+func IsRISCV() bool {
+	return runtime.GOARCH == "riscv64"
+}
+
+// ErrUnsupportedArch is returned for architectures minikube has no VM or container images for
+var ErrUnsupportedArch = errors.New("unsupported architecture")
+
+// ValidateArch returns an error wrapping ErrUnsupportedArch if minikube has no images for EffectiveArch,
+// as is currently the case on riscv64, so that callers can fail clearly instead of proceeding with missing images
+func ValidateArch() error {
+	return validateArch(EffectiveArch())
+}
+
+func validateArch(arch string) error {
+	for _, a := range constants.SupportedArchitectures {
+		if arch == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s (supported: %s)", ErrUnsupportedArch, arch, strings.Join(constants.SupportedArchitectures[:], ", "))
+}
+
 // MinikubeInstalledViaSnap returns true if the minikube binary path includes "snap".
 func MinikubeInstalledViaSnap() bool {
-	ex, err := os.Executable()
+	ex, err := executable()
 	if err != nil {
 		return false
 	}
@@ -102,14 +916,21 @@ func MinikubeInstalledViaSnap() bool {
 }
 
 // DockerInstalledViaSnap returns true if the Docker binary path includes "snap".
+// Snaps are not available on Windows, so it always returns false there.
 func DockerInstalledViaSnap() bool {
-	c := exec.Command("which", "docker")
-	o, err := c.Output()
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	p, err := exec.LookPath("docker")
 	if err != nil {
 		return false
 	}
+	// /snap/bin/docker may be linked from elsewhere on the PATH
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		p = resolved
+	}
 
-	return strings.Contains(string(o), "snap")
+	return strings.Contains(p, "snap")
 }
 
 // GithubActionRunner returns true if running inside a github action runner
@@ -118,19 +939,111 @@ func GithubActionRunner() bool {
 	return os.Getenv("GITHUB_ACTIONS") == "true"
 }
 
-// ImageCacheDir returns the path in the minikube home directory to the container image cache for the current architecture
-func ImageCacheDir() string {
-	return filepath.Join(localpath.MakeMiniPath("cache", "images"), runtime.GOARCH)
+// GitLabCIRunner returns true if running inside a GitLab CI runner
+func GitLabCIRunner() bool {
+	// based on https://docs.gitlab.com/ee/ci/variables/predefined_variables.html
+	return os.Getenv("GITLAB_CI") == "true"
 }
 
-// KICCacheDir returns the path in the minikube home directory to the container node cache for the current architecture
-func KICCacheDir() string {
-	return filepath.Join(localpath.MakeMiniPath("cache", "kic"), runtime.GOARCH)
+// CircleCIRunner returns true if running inside a CircleCI job
+func CircleCIRunner() bool {
+	// based on https://circleci.com/docs/variables/#built-in-environment-variables
+	return os.Getenv("CIRCLECI") == "true"
 }
 
-// ISOCacheDir returns the path in the minikube home directory to the virtual machine image cache for the current architecture
-func ISOCacheDir() string {
-	return filepath.Join(localpath.MakeMiniPath("cache", "iso"), runtime.GOARCH)
+// JenkinsRunner returns true if running inside a Jenkins build
+func JenkinsRunner() bool {
+	// Jenkins does not reliably set CI=true, so look for the variables it always exports
+	// based on https://www.jenkins.io/doc/book/pipeline/jenkinsfile/#using-environment-variables
+	return os.Getenv("JENKINS_URL") != "" || (os.Getenv("BUILD_NUMBER") != "" && os.Getenv("JENKINS_HOME") != "")
+}
+
+// AzurePipelinesRunner returns true if running inside an Azure Pipelines agent
+func AzurePipelinesRunner() bool {
+	// based on https://learn.microsoft.com/en-us/azure/devops/pipelines/build/variables#system-variables
+	return os.Getenv("TF_BUILD") == "True"
+}
+
+// BuildkiteRunner returns true if running inside a Buildkite agent
+func BuildkiteRunner() bool {
+	// based on https://buildkite.com/docs/pipelines/environment-variables
+	return os.Getenv("BUILDKITE") == "true"
+}
+
+// ciRunners are the CI system detectors consulted by CIName, in order of precedence
+var ciRunners = []struct {
+	name   string
+	detect func() bool
+}{
+	{"github", GithubActionRunner},
+	{"gitlab", GitLabCIRunner},
+	{"circleci", CircleCIRunner},
+	{"jenkins", JenkinsRunner},
+	{"azure-pipelines", AzurePipelinesRunner},
+	{"buildkite", BuildkiteRunner},
+}
+
+// ciNameCacheKey is the detect cache entry of CIName
+const ciNameCacheKey = "ci-name"
+
+// CIName returns a short identifier of the CI system minikube is running in, such as "github" or "gitlab",
+// or "" when no known CI system is detected. The result is cached until ResetCIDetection.
+func CIName() string {
+	return cachedString(ciNameCacheKey, 0, func() string {
+		for _, r := range ciRunners {
+			if r.detect() {
+				return r.name
+			}
+		}
+		return ""
+	})
+}
+
+// IsCI returns true if running inside a known CI system or the generic CI environment variable is set
+func IsCI() bool {
+	if CIName() != "" {
+		return true
+	}
+	ci, err := strconv.ParseBool(os.Getenv("CI"))
+	return err == nil && ci
+}
+
+// ResetCIDetection discards the cached CIName result, for use in tests.
+func ResetCIDetection() {
+	forgetDetection(ciNameCacheKey)
+}
+
+// IsGitHubCodespaces returns true if running inside a GitHub Codespace
+func IsGitHubCodespaces() bool {
+	// based on https://docs.github.com/en/codespaces/developing-in-codespaces/default-environment-variables-for-your-codespace
+	return os.Getenv("CODESPACES") == "true"
+}
+
+// IsGitpod returns true if running inside a Gitpod workspace
+func IsGitpod() bool {
+	// based on https://www.gitpod.io/docs/configure/projects/environment-variables#default-environment-variables
+	return os.Getenv("GITPOD_WORKSPACE_ID") != ""
+}
+
+// hostedDevEnvironments are the hosted dev container detectors consulted by HostedDevEnvironment
+var hostedDevEnvironments = []struct {
+	name   string
+	detect func() bool
+}{
+	{"codespaces", IsGitHubCodespaces},
+	{"gitpod", IsGitpod},
+}
+
+// HostedDevEnvironment returns a short identifier of the hosted dev container minikube is running in,
+// such as "codespaces" or "gitpod", or "" when none is detected.
+// These environments are resource constrained, so callers may want to lower defaults.
+func HostedDevEnvironment() string {
+	for _, e := range hostedDevEnvironments {
+		if e.detect() {
+			return e.name
+		}
+	}
+	return ""
 }
 
 // SocketVMNetInstalled returns if socket_vmnet is installed
@@ -147,3 +1060,41 @@ func SocketVMNetInstalled() bool {
 	}
 	return false
 }
+
+// SocketVMNetVersion returns the version of the installed socket_vmnet, e.g. "1.1.2"
+func SocketVMNetVersion() (string, error) {
+	if runtime.GOOS != "darwin" {
+		return "", fmt.Errorf("%w: socket_vmnet is only available on macOS", ErrUnsupportedOS)
+	}
+	if !SocketVMNetInstalled() {
+		return "", fmt.Errorf("socket_vmnet is not installed at %s", viper.GetString("socket-vmnet-path"))
+	}
+	return socketVMNetVersion()
+}
+
+func socketVMNetVersion() (string, error) {
+	bin := socketVMNetBinary()
+	o, err := runCommand(bin, "--version")
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", bin, err)
+	}
+	return parseSocketVMNetVersion(string(o))
+}
+
+// socketVMNetBinary returns the socket_vmnet executable. "socket-vmnet-path" usually names the socket the daemon
+// listens on, so the binary is otherwise looked up next to socket_vmnet_client, which every install ships with.
+func socketVMNetBinary() string {
+	p := viper.GetString("socket-vmnet-path")
+	if st, err := os.Stat(p); err == nil && st.Mode().IsRegular() {
+		return p
+	}
+	if client := viper.GetString("socket-vmnet-client-path"); client != "" {
+		return filepath.Join(filepath.Dir(client), "socket_vmnet")
+	}
+	return "socket_vmnet"
+}
+
+// parseSocketVMNetVersion returns the version number from the output of "socket_vmnet --version"
+func parseSocketVMNetVersion(out string) (string, error) {
+	return parseToolVersion("socket_vmnet", out)
+}
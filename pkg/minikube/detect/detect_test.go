@@ -0,0 +1,1205 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestMemoizedProbe(t *testing.T) {
+	var m memoizedProbe
+	calls := 0
+	probe := func() (bool, error) {
+		calls++
+		return true, fmt.Errorf("probe error")
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := m.get(probe)
+		if !got || err == nil {
+			t.Errorf("get() = %v, %v; want true and the probe error", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("probe ran %d times, want 1", calls)
+	}
+
+	m.reset()
+	if _, err := m.get(probe); err == nil {
+		t.Errorf("get() after reset did not return the probe error")
+	}
+	if calls != 2 {
+		t.Errorf("probe ran %d times after reset, want 2", calls)
+	}
+}
+
+func TestCloudProvider(t *testing.T) {
+	yes := DetectorFunc(func(context.Context) (bool, error) { return true, nil })
+	no := DetectorFunc(func(context.Context) (bool, error) { return false, nil })
+	slow := DetectorFunc(func(ctx context.Context) (bool, error) {
+		select {
+		case <-time.After(time.Second):
+			return true, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	})
+
+	tests := []struct {
+		name   string
+		probes []cloudProbe
+		want   Provider
+	}{
+		{"none", []cloudProbe{{ProviderGCE, no}, {ProviderAWS, no}}, ProviderNone},
+		{"single", []cloudProbe{{ProviderGCE, no}, {ProviderAWS, yes}}, ProviderAWS},
+		{"precedence", []cloudProbe{{ProviderGCE, yes}, {ProviderAWS, yes}}, ProviderGCE},
+		{"deadline", []cloudProbe{{ProviderGCE, slow}, {ProviderAzure, yes}}, ProviderAzure},
+	}
+
+	origProbes, origDeadline := cloudProbes, cloudProbeDeadline
+	defer func() {
+		cloudProbes, cloudProbeDeadline = origProbes, origDeadline
+		ResetCloudDetection()
+	}()
+	cloudProbeDeadline = 100 * time.Millisecond
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ResetCloudDetection()
+			cloudProbes = tc.probes
+			if got := CloudProvider(); got != tc.want {
+				t.Errorf("CloudProvider() = %q, want %q", got, tc.want)
+			}
+			// the result must be memoized
+			cloudProbes = []cloudProbe{{ProviderOCI, yes}}
+			if got := CloudProvider(); got != tc.want {
+				t.Errorf("CloudProvider() not memoized: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseGCEZone(t *testing.T) {
+	tests := []struct {
+		metadata   string
+		zone       string
+		region     string
+		shouldFail bool
+	}{
+		{"projects/123456789/zones/us-central1-a", "us-central1-a", "us-central1", false},
+		{"projects/123456789/zones/europe-west4-b\n", "europe-west4-b", "europe-west4", false},
+		{"asia-northeast1-c", "asia-northeast1-c", "asia-northeast1", false},
+		{"projects/123456789/zones/", "", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.metadata, func(t *testing.T) {
+			zone, err := parseGCEZone(tc.metadata)
+			if err != nil {
+				if !tc.shouldFail {
+					t.Fatalf("parseGCEZone(%q) returned unexpected error: %v", tc.metadata, err)
+				}
+				return
+			}
+			if tc.shouldFail {
+				t.Fatalf("parseGCEZone(%q) = %q, expected an error", tc.metadata, zone)
+			}
+			if zone != tc.zone {
+				t.Errorf("parseGCEZone(%q) = %q, want %q", tc.metadata, zone, tc.zone)
+			}
+			region, err := gceRegionFromZone(zone)
+			if err != nil {
+				t.Fatalf("gceRegionFromZone(%q) returned unexpected error: %v", zone, err)
+			}
+			if region != tc.region {
+				t.Errorf("gceRegionFromZone(%q) = %q, want %q", zone, region, tc.region)
+			}
+		})
+	}
+}
+
+func TestIsOnBareMetalInCloud(t *testing.T) {
+	origProbes := cloudProbes
+	defer func() {
+		cloudProbes = origProbes
+		ResetCloudDetection()
+	}()
+	yes := DetectorFunc(func(context.Context) (bool, error) { return true, nil })
+
+	ResetCloudDetection()
+	cloudProbes = []cloudProbe{{ProviderAWS, yes}}
+	if IsOnBareMetal() {
+		t.Errorf("IsOnBareMetal() = true while a cloud provider was detected")
+	}
+
+	ResetCloudDetection()
+	cloudProbes = []cloudProbe{{ProviderEquinixMetal, yes}}
+	if !IsOnBareMetal() {
+		t.Errorf("IsOnBareMetal() = false on Equinix Metal, which only rents out dedicated servers")
+	}
+}
+
+func TestCloudProviderWithContextCanceled(t *testing.T) {
+	origProbes := cloudProbes
+	defer func() { cloudProbes = origProbes }()
+	hung := DetectorFunc(func(ctx context.Context) (bool, error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	})
+	cloudProbes = []cloudProbe{{ProviderGCE, hung}, {ProviderAWS, hung}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if got := CloudProviderWithContext(ctx); got != ProviderNone {
+		t.Errorf("CloudProviderWithContext() = %q, want %q", got, ProviderNone)
+	}
+	if elapsed := time.Since(start); elapsed >= cloudProbeDeadline {
+		t.Errorf("CloudProviderWithContext() returned after %s, want it to stop on cancellation", elapsed)
+	}
+}
+
+func TestMetadataRequestCanceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := probeMetadata(ctx, srv.URL, nil, time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("probeMetadata() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// fakeMetadataServer points the cloud detectors at a local server serving the given routes
+func fakeMetadataServer(t testing.TB, routes map[string]http.HandlerFunc) {
+	t.Helper()
+	mux := http.NewServeMux()
+	for path, h := range routes {
+		mux.HandleFunc(path, h)
+	}
+	srv := httptest.NewServer(mux)
+
+	origLinkLocal, origGCE, origEquinix := linkLocalMetadataURL, gceMetadataURL, equinixMetadataURL
+	linkLocalMetadataURL, gceMetadataURL, equinixMetadataURL = srv.URL, srv.URL, srv.URL+"/metadata"
+	ResetCloudDetection()
+	t.Cleanup(func() {
+		srv.Close()
+		linkLocalMetadataURL, gceMetadataURL, equinixMetadataURL = origLinkLocal, origGCE, origEquinix
+		ResetCloudDetection()
+	})
+}
+
+// respond returns a handler that checks for a request header and replies with body
+func respond(method, header, value, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method || (header != "" && r.Header.Get(header) != value) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestCloudDetectors(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		detect func() bool
+		want   bool
+	}{
+		{
+			name:   "nothing served",
+			routes: map[string]http.HandlerFunc{},
+			detect: func() bool {
+				return IsOnAWS() || IsOnAzure() || IsOnDigitalOcean() || IsOnOCI() || IsOnIBMCloud() || IsOnOpenStack()
+			},
+			want: false,
+		},
+		{
+			name: "gce",
+			routes: map[string]http.HandlerFunc{"/": func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Metadata-Flavor", "Google")
+			}},
+			detect: IsOnGCE,
+			want:   true,
+		},
+		{
+			name: "aws imdsv2",
+			routes: map[string]http.HandlerFunc{
+				"/latest/api/token":             respond(http.MethodPut, "X-aws-ec2-metadata-token-ttl-seconds", "21600", "token"),
+				"/latest/meta-data/instance-id": respond(http.MethodGet, "X-aws-ec2-metadata-token", "token", "i-0123456789abcdef0"),
+			},
+			detect: IsOnAWS,
+			want:   true,
+		},
+		{
+			name: "aws imdsv1",
+			routes: map[string]http.HandlerFunc{
+				"/latest/meta-data/instance-id": respond(http.MethodGet, "", "", "i-0123456789abcdef0"),
+			},
+			detect: IsOnAWS,
+			want:   true,
+		},
+		{
+			name: "azure",
+			routes: map[string]http.HandlerFunc{
+				"/metadata/instance": respond(http.MethodGet, "Metadata", "true", `{"compute":{"azEnvironment":"AzurePublicCloud"}}`),
+			},
+			detect: IsOnAzure,
+			want:   true,
+		},
+		{
+			name: "azure without environment",
+			routes: map[string]http.HandlerFunc{
+				"/metadata/instance": respond(http.MethodGet, "Metadata", "true", `{"compute":{}}`),
+			},
+			detect: IsOnAzure,
+			want:   false,
+		},
+		{
+			name: "digitalocean",
+			routes: map[string]http.HandlerFunc{
+				"/metadata/v1/id": respond(http.MethodGet, "", "", "2756294"),
+			},
+			detect: IsOnDigitalOcean,
+			want:   true,
+		},
+		{
+			name: "digitalocean non-numeric id",
+			routes: map[string]http.HandlerFunc{
+				"/metadata/v1/id": respond(http.MethodGet, "", "", "i-0123456789abcdef0"),
+			},
+			detect: IsOnDigitalOcean,
+			want:   false,
+		},
+		{
+			name: "oci",
+			routes: map[string]http.HandlerFunc{
+				"/opc/v2/instance/": respond(http.MethodGet, "Authorization", "Bearer Oracle", `{"canonicalRegionName":"us-ashburn-1"}`),
+			},
+			detect: IsOnOCI,
+			want:   true,
+		},
+		{
+			name: "ibm cloud",
+			routes: map[string]http.HandlerFunc{
+				"/instance_identity/v1/token": respond(http.MethodPut, "Metadata-Flavor", "ibm", `{"access_token":"token"}`),
+				"/metadata/v1/instance":       respond(http.MethodGet, "Authorization", "Bearer token", `{"id":"0717_1e09281b","crn":"crn:v1:bluemix:public:is:us-south-1:a/123::instance:0717_1e09281b"}`),
+			},
+			detect: IsOnIBMCloud,
+			want:   true,
+		},
+		{
+			name: "openstack",
+			routes: map[string]http.HandlerFunc{
+				"/openstack/latest/meta_data.json": respond(http.MethodGet, "", "", `{"uuid":"d8e02d56-2648-49a3-bf97-6be8f1204f38","name":"test","availability_zone":"nova"}`),
+			},
+			detect: IsOnOpenStack,
+			want:   true,
+		},
+		{
+			name: "openstack without uuid",
+			routes: map[string]http.HandlerFunc{
+				"/openstack/latest/meta_data.json": respond(http.MethodGet, "", "", `{"name":"test"}`),
+			},
+			detect: IsOnOpenStack,
+			want:   false,
+		},
+		{
+			name: "equinix metal",
+			routes: map[string]http.HandlerFunc{
+				"/metadata": respond(http.MethodGet, "", "", `{"id":"0b1d6b5e","class":"c3.small.x86","plan":"c3.small.x86","facility":"da11"}`),
+			},
+			detect: IsOnEquinixMetal,
+			want:   true,
+		},
+		{
+			name: "vultr",
+			routes: map[string]http.HandlerFunc{
+				"/v1.json": respond(http.MethodGet, "", "", `{"instanceid":"a747bfz6385e","hostname":"vultr.guest","region":{"regioncode":"EWR"}}`),
+			},
+			detect: IsOnVultr,
+			want:   true,
+		},
+		{
+			name: "linode",
+			routes: map[string]http.HandlerFunc{
+				"/v1/token":    respond(http.MethodPut, "Metadata-Token-Expiry-Seconds", "300", "token"),
+				"/v1/instance": respond(http.MethodGet, "Metadata-Token", "token", `{"id":12345678,"label":"linode","region":"us-iad","type":"g6-standard-1"}`),
+			},
+			detect: IsOnLinode,
+			want:   true,
+		},
+		{
+			name: "aws is neither vultr nor linode",
+			routes: map[string]http.HandlerFunc{
+				"/latest/api/token":             respond(http.MethodPut, "", "", "token"),
+				"/latest/meta-data/instance-id": respond(http.MethodGet, "", "", "i-0123456789abcdef0"),
+			},
+			detect: func() bool { return IsOnVultr() || IsOnLinode() },
+			want:   false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, tc.routes)
+			if got := tc.detect(); got != tc.want {
+				t.Errorf("detect() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+// stalledMetadataServer points the link-local cloud detectors at a server that never answers
+func stalledMetadataServer(t *testing.T) {
+	t.Helper()
+	release := make(chan struct{})
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		},
+	})
+	t.Cleanup(func() { close(release) })
+}
+
+func TestIsOnAWS(t *testing.T) {
+	instanceID := respond(http.MethodGet, "", "", "i-0123456789abcdef0")
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   bool
+	}{
+		{
+			name: "imdsv2 token",
+			routes: map[string]http.HandlerFunc{
+				"/latest/api/token":             respond(http.MethodPut, "X-aws-ec2-metadata-token-ttl-seconds", "21600", "token"),
+				"/latest/meta-data/instance-id": respond(http.MethodGet, "X-aws-ec2-metadata-token", "token", "i-0123456789abcdef0"),
+			},
+			want: true,
+		},
+		{
+			name: "imdsv1 fallback when the token is refused",
+			routes: map[string]http.HandlerFunc{
+				"/latest/api/token": func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+				},
+				"/latest/meta-data/instance-id": instanceID,
+			},
+			want: true,
+		},
+		{
+			name: "not an instance id",
+			routes: map[string]http.HandlerFunc{
+				"/latest/meta-data/instance-id": respond(http.MethodGet, "", "", "2756294"),
+			},
+			want: false,
+		},
+		{
+			name:   "no metadata service",
+			routes: map[string]http.HandlerFunc{},
+			want:   false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, tc.routes)
+			if got := IsOnAWS(); got != tc.want {
+				t.Errorf("IsOnAWS() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsOnAWSUnreachable(t *testing.T) {
+	stalledMetadataServer(t)
+	start := time.Now()
+	if IsOnAWS() {
+		t.Error("IsOnAWS() = true for a metadata service that never answers")
+	}
+	// the token request and the IMDSv1 fallback must not both wait for the timeout
+	if elapsed := time.Since(start); elapsed > 2*linkLocalMetadataTimeout+time.Second {
+		t.Errorf("IsOnAWS() took %s for an unreachable metadata service", elapsed)
+	}
+}
+
+func TestIsOnAzure(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"public cloud", `{"compute":{"azEnvironment":"AzurePublicCloud","location":"westeurope"}}`, true},
+		{"without environment", `{"compute":{"location":"westeurope"}}`, false},
+		{"malformed", `{"compute":`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, map[string]http.HandlerFunc{
+				"/metadata/instance": func(w http.ResponseWriter, r *http.Request) {
+					// IMDS rejects requests without the header and API version
+					if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("api-version") == "" {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					fmt.Fprint(w, tc.body)
+				},
+			})
+			if got := IsOnAzure(); got != tc.want {
+				t.Errorf("IsOnAzure() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsOnAzureParallel(t *testing.T) {
+	requests := 0
+	var mu sync.Mutex
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/metadata/instance": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests++
+			mu.Unlock()
+			fmt.Fprint(w, `{"compute":{"azEnvironment":"AzurePublicCloud"}}`)
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !IsOnAzure() {
+				t.Error("IsOnAzure() = false on Azure")
+			}
+		}()
+	}
+	wg.Wait()
+	if requests != 1 {
+		t.Errorf("parallel IsOnAzure() calls sent %d requests, want 1", requests)
+	}
+}
+
+func TestIsOnAzureUnreachable(t *testing.T) {
+	stalledMetadataServer(t)
+	start := time.Now()
+	if IsOnAzure() {
+		t.Error("IsOnAzure() = true for a metadata service that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > linkLocalMetadataTimeout+time.Second {
+		t.Errorf("IsOnAzure() took %s for an unreachable metadata service", elapsed)
+	}
+}
+
+func TestIsOnDigitalOcean(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   bool
+	}{
+		{"droplet", map[string]http.HandlerFunc{"/metadata/v1/id": respond(http.MethodGet, "", "", "2756294\n")}, true},
+		{"non-numeric id", map[string]http.HandlerFunc{"/metadata/v1/id": respond(http.MethodGet, "", "", "i-0123456789abcdef0")}, false},
+		{"empty id", map[string]http.HandlerFunc{"/metadata/v1/id": respond(http.MethodGet, "", "", "")}, false},
+		{
+			// EC2 answers on the same address, but not under /metadata/v1/
+			name: "aws",
+			routes: map[string]http.HandlerFunc{
+				"/latest/api/token":             respond(http.MethodPut, "", "", "token"),
+				"/latest/meta-data/instance-id": respond(http.MethodGet, "", "", "i-0123456789abcdef0"),
+			},
+			want: false,
+		},
+		{
+			// so does Azure, which serves JSON under /metadata/
+			name:   "azure",
+			routes: map[string]http.HandlerFunc{"/metadata/": respond(http.MethodGet, "", "", `{"compute":{"azEnvironment":"AzurePublicCloud"}}`)},
+			want:   false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, tc.routes)
+			if got := IsOnDigitalOcean(); got != tc.want {
+				t.Errorf("IsOnDigitalOcean() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsOnDigitalOceanUnreachable(t *testing.T) {
+	stalledMetadataServer(t)
+	start := time.Now()
+	if IsOnDigitalOcean() {
+		t.Error("IsOnDigitalOcean() = true for a metadata service that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > linkLocalMetadataTimeout+time.Second {
+		t.Errorf("IsOnDigitalOcean() took %s for an unreachable metadata service", elapsed)
+	}
+}
+
+func TestIsOnOCI(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		body          string
+		want          bool
+	}{
+		{"instance", "Bearer Oracle", `{"id":"ocid1.instance.oc1.iad.abc","canonicalRegionName":"us-ashburn-1"}`, true},
+		{"without region", "Bearer Oracle", `{"id":"ocid1.instance.oc1.iad.abc"}`, false},
+		{"malformed", "Bearer Oracle", `[]`, false},
+		// another service on the same address refusing the header
+		{"refused", "", `{"canonicalRegionName":"us-ashburn-1"}`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, map[string]http.HandlerFunc{
+				"/opc/v2/instance/": func(w http.ResponseWriter, r *http.Request) {
+					// IMDSv2 refuses requests without the header
+					if tc.authorization != "" && r.Header.Get("Authorization") == tc.authorization {
+						fmt.Fprint(w, tc.body)
+						return
+					}
+					w.WriteHeader(http.StatusUnauthorized)
+				},
+			})
+			if got := IsOnOCI(); got != tc.want {
+				t.Errorf("IsOnOCI() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsOnOCIUnreachable(t *testing.T) {
+	stalledMetadataServer(t)
+	start := time.Now()
+	if IsOnOCI() {
+		t.Error("IsOnOCI() = true for a metadata service that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > linkLocalMetadataTimeout+time.Second {
+		t.Errorf("IsOnOCI() took %s for an unreachable metadata service", elapsed)
+	}
+}
+
+func TestIsOnIBMCloud(t *testing.T) {
+	// token checks the handshake IBM Cloud requires before issuing an access token
+	token := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut || r.Header.Get("Metadata-Flavor") != "ibm" || r.URL.Query().Get("version") == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, body)
+		}
+	}
+	instance := `{"id":"0717_1e09281b","crn":"crn:v1:bluemix:public:is:us-south-1:a/123::instance:0717_1e09281b"}`
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   bool
+	}{
+		{
+			name: "instance",
+			routes: map[string]http.HandlerFunc{
+				"/instance_identity/v1/token": token(`{"access_token":"token"}`),
+				"/metadata/v1/instance":       respond(http.MethodGet, "Authorization", "Bearer token", instance),
+			},
+			want: true,
+		},
+		{
+			name: "no access token",
+			routes: map[string]http.HandlerFunc{
+				"/instance_identity/v1/token": token(`{}`),
+				"/metadata/v1/instance":       respond(http.MethodGet, "", "", instance),
+			},
+			want: false,
+		},
+		{
+			name: "token refused",
+			routes: map[string]http.HandlerFunc{
+				"/instance_identity/v1/token": func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) },
+				"/metadata/v1/instance":       respond(http.MethodGet, "", "", instance),
+			},
+			want: false,
+		},
+		{
+			name: "not a crn",
+			routes: map[string]http.HandlerFunc{
+				"/instance_identity/v1/token": token(`{"access_token":"token"}`),
+				"/metadata/v1/instance":       respond(http.MethodGet, "Authorization", "Bearer token", `{"id":"0717_1e09281b","crn":""}`),
+			},
+			want: false,
+		},
+		{
+			name: "malformed instance",
+			routes: map[string]http.HandlerFunc{
+				"/instance_identity/v1/token": token(`{"access_token":"token"}`),
+				"/metadata/v1/instance":       respond(http.MethodGet, "Authorization", "Bearer token", `{"id":`),
+			},
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, tc.routes)
+			if got := IsOnIBMCloud(); got != tc.want {
+				t.Errorf("IsOnIBMCloud() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsOnIBMCloudUnreachable(t *testing.T) {
+	stalledMetadataServer(t)
+	start := time.Now()
+	if IsOnIBMCloud() {
+		t.Error("IsOnIBMCloud() = true for a metadata service that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > linkLocalMetadataTimeout+time.Second {
+		t.Errorf("IsOnIBMCloud() took %s for an unreachable metadata service", elapsed)
+	}
+}
+
+func TestIsOnGCEWithContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		flavor string
+		want   bool
+	}{
+		{"metadata server", "Google", true},
+		{"other server", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMetadataServer(t, map[string]http.HandlerFunc{
+				"/": func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Metadata-Flavor", tc.flavor)
+				},
+			})
+			if got := IsOnGCEWithContext(context.Background()); got != tc.want {
+				t.Errorf("IsOnGCEWithContext() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsOnGCEWithContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			w.Header().Set("Metadata-Flavor", "Google")
+		},
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if IsOnGCEWithContext(ctx) {
+		t.Error("IsOnGCEWithContext() = true for a metadata server answering after the deadline")
+	}
+	if elapsed := time.Since(start); elapsed >= gceMetadataTimeout {
+		t.Errorf("IsOnGCEWithContext() returned after %s, want it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestAWSMetadata(t *testing.T) {
+	tokens := 0
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/latest/api/token": func(w http.ResponseWriter, r *http.Request) {
+			tokens++
+			fmt.Fprint(w, "token")
+		},
+		"/latest/meta-data/instance-id":      respond(http.MethodGet, "X-aws-ec2-metadata-token", "token", "i-0123456789abcdef0"),
+		"/latest/meta-data/instance-type":    respond(http.MethodGet, "X-aws-ec2-metadata-token", "token", "t3.micro"),
+		"/latest/meta-data/placement/region": respond(http.MethodGet, "X-aws-ec2-metadata-token", "token", "us-east-1"),
+	})
+
+	instanceType, err := AWSInstanceType()
+	if err != nil || instanceType != "t3.micro" {
+		t.Errorf("AWSInstanceType() = %q, %v; want \"t3.micro\"", instanceType, err)
+	}
+	region, err := AWSRegion()
+	if err != nil || region != "us-east-1" {
+		t.Errorf("AWSRegion() = %q, %v; want \"us-east-1\"", region, err)
+	}
+	if tokens != 1 {
+		t.Errorf("requested %d IMDSv2 tokens, want 1", tokens)
+	}
+}
+
+func TestAWSMetadataOffAWS(t *testing.T) {
+	queried := false
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/latest/meta-data/": func(w http.ResponseWriter, r *http.Request) {
+			queried = true
+			w.WriteHeader(http.StatusNotFound)
+		},
+	})
+
+	if v, err := AWSInstanceType(); err == nil {
+		t.Errorf("AWSInstanceType() = %q off AWS, want an error", v)
+	}
+	if v, err := AWSRegion(); err == nil {
+		t.Errorf("AWSRegion() = %q off AWS, want an error", v)
+	}
+	if !queried {
+		t.Error("the instance id was not checked before answering")
+	}
+}
+
+func TestAWSMetadataTokenRefresh(t *testing.T) {
+	tokens := 0
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/latest/api/token": func(w http.ResponseWriter, r *http.Request) {
+			tokens++
+			fmt.Fprintf(w, "token%d", tokens)
+		},
+		"/latest/meta-data/instance-id":   respond(http.MethodGet, "", "", "i-0123456789abcdef0"),
+		"/latest/meta-data/instance-type": respond(http.MethodGet, "", "", "t3.micro"),
+	})
+
+	if _, err := AWSInstanceType(); err != nil {
+		t.Fatalf("AWSInstanceType() returned unexpected error: %v", err)
+	}
+	// an expired token is replaced rather than reused
+	awsTokenMu.Lock()
+	awsTokenExpiry = time.Now().Add(-time.Second)
+	awsTokenMu.Unlock()
+	if _, err := AWSInstanceType(); err != nil {
+		t.Fatalf("AWSInstanceType() returned unexpected error: %v", err)
+	}
+	if tokens != 2 {
+		t.Errorf("requested %d IMDSv2 tokens, want 2", tokens)
+	}
+}
+
+func TestGCEZoneMetadata(t *testing.T) {
+	fakeMetadataServer(t, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Metadata-Flavor", "Google")
+		},
+		"/computeMetadata/v1/instance/zone": respond(http.MethodGet, "Metadata-Flavor", "Google", "projects/123456789/zones/us-central1-a"),
+	})
+
+	region, err := GCERegion()
+	if err != nil || region != "us-central1" {
+		t.Errorf("GCERegion() = %q, %v; want \"us-central1\"", region, err)
+	}
+}
+
+func TestCIRunners(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		detect func() bool
+		want   bool
+	}{
+		{"github", map[string]string{"GITHUB_ACTIONS": "true"}, GithubActionRunner, true},
+		{"github unset", map[string]string{"GITHUB_ACTIONS": ""}, GithubActionRunner, false},
+		{"gitlab", map[string]string{"GITLAB_CI": "true"}, GitLabCIRunner, true},
+		{"gitlab unset", map[string]string{"GITLAB_CI": ""}, GitLabCIRunner, false},
+		{"circleci", map[string]string{"CIRCLECI": "true"}, CircleCIRunner, true},
+		{"circleci unset", map[string]string{"CIRCLECI": ""}, CircleCIRunner, false},
+		{"jenkins url", map[string]string{"JENKINS_URL": "https://ci.example.com/", "BUILD_NUMBER": "", "JENKINS_HOME": ""}, JenkinsRunner, true},
+		{"jenkins build", map[string]string{"JENKINS_URL": "", "BUILD_NUMBER": "42", "JENKINS_HOME": "/var/lib/jenkins"}, JenkinsRunner, true},
+		{"build number only", map[string]string{"JENKINS_URL": "", "BUILD_NUMBER": "42", "JENKINS_HOME": ""}, JenkinsRunner, false},
+		{"azure pipelines", map[string]string{"TF_BUILD": "True"}, AzurePipelinesRunner, true},
+		{"azure pipelines unset", map[string]string{"TF_BUILD": ""}, AzurePipelinesRunner, false},
+		{"buildkite", map[string]string{"BUILDKITE": "true"}, BuildkiteRunner, true},
+		{"buildkite unset", map[string]string{"BUILDKITE": ""}, BuildkiteRunner, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			if got := tc.detect(); got != tc.want {
+				t.Errorf("detect() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCIName(t *testing.T) {
+	ciVars := []string{"GITHUB_ACTIONS", "GITLAB_CI", "CIRCLECI", "JENKINS_URL", "BUILD_NUMBER", "JENKINS_HOME", "TF_BUILD", "BUILDKITE", "CI"}
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+		isCI bool
+	}{
+		{"none", map[string]string{}, "", false},
+		{"generic", map[string]string{"CI": "true"}, "", true},
+		{"generic false", map[string]string{"CI": "false"}, "", false},
+		{"github", map[string]string{"GITHUB_ACTIONS": "true", "CI": "true"}, "github", true},
+		{"gitlab", map[string]string{"GITLAB_CI": "true"}, "gitlab", true},
+		{"circleci", map[string]string{"CIRCLECI": "true"}, "circleci", true},
+		{"jenkins", map[string]string{"JENKINS_URL": "https://ci.example.com/"}, "jenkins", true},
+		{"azure pipelines", map[string]string{"TF_BUILD": "True"}, "azure-pipelines", true},
+		{"buildkite", map[string]string{"BUILDKITE": "true", "CI": "true"}, "buildkite", true},
+	}
+
+	defer ResetCIDetection()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range ciVars {
+				t.Setenv(k, tc.env[k])
+			}
+			ResetCIDetection()
+			if got := CIName(); got != tc.want {
+				t.Errorf("CIName() = %q, want %q", got, tc.want)
+			}
+			if got := IsCI(); got != tc.isCI {
+				t.Errorf("IsCI() = %t, want %t", got, tc.isCI)
+			}
+		})
+	}
+}
+
+func TestHostedDevEnvironment(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"none", map[string]string{}, ""},
+		{"codespaces", map[string]string{"CODESPACES": "true"}, "codespaces"},
+		{"codespaces false", map[string]string{"CODESPACES": "false"}, ""},
+		{"gitpod", map[string]string{"GITPOD_WORKSPACE_ID": "minikube-abc123"}, "gitpod"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range []string{"CODESPACES", "GITPOD_WORKSPACE_ID"} {
+				t.Setenv(k, tc.env[k])
+			}
+			if got := HostedDevEnvironment(); got != tc.want {
+				t.Errorf("HostedDevEnvironment() = %q, want %q", got, tc.want)
+			}
+			if got := IsGitHubCodespaces(); got != (tc.want == "codespaces") {
+				t.Errorf("IsGitHubCodespaces() = %t", got)
+			}
+			if got := IsGitpod(); got != (tc.want == "gitpod") {
+				t.Errorf("IsGitpod() = %t", got)
+			}
+		})
+	}
+}
+
+// fakeHostRoot points the file based detectors at a temporary tree populated with the given files
+func fakeHostRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		p := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create fixture dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write fixture %s: %v", path, err)
+		}
+	}
+
+	orig := hostRoot
+	hostRoot = root
+	t.Cleanup(func() { hostRoot = orig })
+	return root
+}
+
+func TestInContainer(t *testing.T) {
+	hostCgroupV1 := "12:memory:/user.slice\n11:cpu,cpuacct:/user.slice\n1:name=systemd:/init.scope\n"
+	hostMountinfo := "25 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw\n" +
+		"1200 25 0:52 / /var/lib/docker/overlay2/0b1d/merged rw,relatime - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/ABC\n"
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		container string
+		want      bool
+	}{
+		{"host", map[string]string{"/proc/1/cgroup": hostCgroupV1, "/proc/self/mountinfo": hostMountinfo}, "", false},
+		{"dockerenv", map[string]string{"/.dockerenv": ""}, "", true},
+		{"containerenv", map[string]string{"/run/.containerenv": ""}, "", true},
+		{"env hint", map[string]string{}, "podman", true},
+		{"cgroup v1 docker", map[string]string{"/proc/1/cgroup": "12:memory:/docker/3601745b3bd54d9780436faa5f0e4f72bb46231663bb99a6bb892764917832c2\n"}, "", true},
+		{"cgroup v1 kubernetes", map[string]string{"/proc/1/cgroup": "11:cpu,cpuacct:/kubepods/burstable/pod7d5b4d3e/0b1d\n"}, "", true},
+		{"cgroup v2 docker", map[string]string{
+			"/proc/1/cgroup":       "0::/\n",
+			"/proc/self/mountinfo": "812 711 0:49 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/ABC\n",
+		}, "", true},
+		{"cgroup v2 containerd hostname", map[string]string{
+			"/proc/1/cgroup":       "0::/\n",
+			"/proc/self/mountinfo": "820 812 259:2 /var/lib/containerd/io.containerd.grpc.v1.cri/sandboxes/0b1d/hostname /etc/hostname rw - ext4 /dev/sda1 rw\n",
+		}, "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			t.Setenv("container", tc.container)
+			if got := inContainer(); got != tc.want {
+				t.Errorf("inContainer() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsInKubernetesPod(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		host  string
+		want  bool
+	}{
+		{"outside", map[string]string{}, "", false},
+		{"service env", map[string]string{}, "10.96.0.1", true},
+		{"service account token", map[string]string{"/var/run/secrets/kubernetes.io/serviceaccount/token": "token"}, "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			t.Setenv("KUBERNETES_SERVICE_HOST", tc.host)
+			if got := IsInKubernetesPod(); got != tc.want {
+				t.Errorf("IsInKubernetesPod() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeCommand stubs runCommandContext, answering each "name arg..." command line from outputs
+// and failing any other command as if the program was not installed
+func fakeCommand(t testing.TB, outputs map[string]string) {
+	t.Helper()
+	orig := runCommandContext
+	runCommandContext = func(_ context.Context, name string, arg ...string) ([]byte, error) {
+		cmd := strings.Join(append([]string{name}, arg...), " ")
+		if out, ok := outputs[cmd]; ok {
+			return []byte(out), nil
+		}
+		return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	t.Cleanup(func() { runCommandContext = orig })
+}
+
+func TestValidateArch(t *testing.T) {
+	tests := []struct {
+		arch        string
+		unsupported bool
+	}{
+		{"amd64", false},
+		{"arm64", false},
+		{"arm", false},
+		{"s390x", false},
+		{"riscv64", true},
+		{"mips64", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.arch, func(t *testing.T) {
+			err := validateArch(tc.arch)
+			if got := errors.Is(err, ErrUnsupportedArch); got != tc.unsupported {
+				t.Errorf("validateArch(%q) = %v, want unsupported: %t", tc.arch, err, tc.unsupported)
+			}
+		})
+	}
+}
+
+func TestCacheDirsUseRuntimeArch(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	for name, dir := range map[string]string{"images": ImageCacheDir(), "kic": KICCacheDir(), "iso": ISOCacheDir()} {
+		if filepath.Base(dir) != runtime.GOARCH {
+			t.Errorf("%s cache dir %q does not end in %q", name, dir, runtime.GOARCH)
+		}
+		if filepath.Base(filepath.Dir(dir)) != name {
+			t.Errorf("%s cache dir %q is not under a %q directory", name, dir, name)
+		}
+	}
+	if IsRISCV() != (runtime.GOARCH == "riscv64") {
+		t.Errorf("IsRISCV() = %t on %s", IsRISCV(), runtime.GOARCH)
+	}
+}
+
+func TestWSLDistroName(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu-22.04")
+	if got := WSLDistroName(); got != "Ubuntu-22.04" {
+		t.Errorf("WSLDistroName() = %q, want %q", got, "Ubuntu-22.04")
+	}
+
+	t.Setenv("WSL_DISTRO_NAME", "")
+	if got := WSLDistroName(); got != "" {
+		t.Errorf("WSLDistroName() = %q, want empty outside WSL", got)
+	}
+}
+
+func TestDockerInstalledViaSnap(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("snaps are not available on windows")
+	}
+
+	// writeDocker creates an executable docker in dir and returns its path
+	writeDocker := func(t *testing.T, dir string) string {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(dir, "docker")
+		if err := os.WriteFile(p, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	t.Run("linked from snap", func(t *testing.T) {
+		root := t.TempDir()
+		target := writeDocker(t, filepath.Join(root, "snap", "docker", "current", "bin"))
+		bin := filepath.Join(root, "bin")
+		if err := os.MkdirAll(bin, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(bin, "docker")); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PATH", bin)
+		if !DockerInstalledViaSnap() {
+			t.Error("DockerInstalledViaSnap() = false, want true for a link into a snap")
+		}
+	})
+
+	t.Run("package", func(t *testing.T) {
+		t.Setenv("PATH", filepath.Dir(writeDocker(t, filepath.Join(t.TempDir(), "usr", "bin"))))
+		if DockerInstalledViaSnap() {
+			t.Error("DockerInstalledViaSnap() = true, want false")
+		}
+	})
+
+	t.Run("not installed", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		if DockerInstalledViaSnap() {
+			t.Error("DockerInstalledViaSnap() = true, want false without docker")
+		}
+	})
+}
+
+func TestParseSocketVMNetVersion(t *testing.T) {
+	tests := []struct {
+		out     string
+		want    string
+		wantErr bool
+	}{
+		{"socket_vmnet version v1.1.2\n", "1.1.2", false},
+		{"socket_vmnet version 1.1.0-rc1\n", "1.1.0", false},
+		{"v1.0.0\n", "1.0.0", false},
+		{"Usage: socket_vmnet [OPTION]... SOCKET\n", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := parseSocketVMNetVersion(tc.out)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("parseSocketVMNetVersion(%q) error = %v, wantErr %v", tc.out, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("parseSocketVMNetVersion(%q) = %q, want %q", tc.out, got, tc.want)
+		}
+	}
+}
+
+func TestSocketVMNetVersion(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "socket_vmnet")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake socket_vmnet: %v", err)
+	}
+	defer viper.Reset()
+
+	t.Run("path to the binary", func(t *testing.T) {
+		viper.Set("socket-vmnet-path", binary)
+		viper.Set("socket-vmnet-client-path", "/opt/socket_vmnet/bin/socket_vmnet_client")
+		fakeCommand(t, map[string]string{binary + " --version": "socket_vmnet version v1.1.2\n"})
+		got, err := socketVMNetVersion()
+		if err != nil {
+			t.Fatalf("socketVMNetVersion() error = %v", err)
+		}
+		if got != "1.1.2" {
+			t.Errorf("socketVMNetVersion() = %q, want %q", got, "1.1.2")
+		}
+	})
+	t.Run("path to the socket", func(t *testing.T) {
+		viper.Set("socket-vmnet-path", "/var/run/socket_vmnet")
+		viper.Set("socket-vmnet-client-path", "/opt/socket_vmnet/bin/socket_vmnet_client")
+		bin := filepath.Join("/opt/socket_vmnet/bin", "socket_vmnet")
+		fakeCommand(t, map[string]string{bin + " --version": "socket_vmnet version v1.1.3\n"})
+		got, err := socketVMNetVersion()
+		if err != nil {
+			t.Fatalf("socketVMNetVersion() error = %v", err)
+		}
+		if got != "1.1.3" {
+			t.Errorf("socketVMNetVersion() = %q, want %q", got, "1.1.3")
+		}
+	})
+	t.Run("no version flag", func(t *testing.T) {
+		viper.Set("socket-vmnet-path", binary)
+		fakeCommand(t, map[string]string{})
+		if _, err := socketVMNetVersion(); err == nil {
+			t.Error("socketVMNetVersion() succeeded without a version")
+		}
+	})
+}
+
+func TestSocketVMNetVersionUnsupported(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("socket_vmnet is supported on macOS")
+	}
+	if _, err := SocketVMNetVersion(); !errors.Is(err, ErrUnsupportedOS) {
+		t.Errorf("SocketVMNetVersion() error = %v, want ErrUnsupportedOS", err)
+	}
+}
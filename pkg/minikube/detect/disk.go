@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+// AvailableDiskSpaceMiB returns the disk space in MiB available to the current user on the filesystem holding path.
+// If path does not exist yet, the filesystem of its nearest existing parent is checked instead.
+func AvailableDiskSpaceMiB(path string) (int64, error) {
+	dir, err := nearestExistingDir(path)
+	if err != nil {
+		return 0, err
+	}
+	bytes, err := availableDiskSpace(dir)
+	if err != nil {
+		return 0, fmt.Errorf("checking free space of %s: %w", dir, err)
+	}
+	return int64(bytes >> 20), nil
+}
+
+// MinikubeHomeFreeSpaceMiB returns the disk space in MiB available in the minikube home directory
+func MinikubeHomeFreeSpaceMiB() (int64, error) {
+	return AvailableDiskSpaceMiB(localpath.MiniPath())
+}
+
+// nearestExistingDir returns path, or its closest parent that exists
+func nearestExistingDir(path string) (string, error) {
+	p, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", fmt.Errorf("no existing parent directory for %s", path)
+		}
+		p = parent
+	}
+}
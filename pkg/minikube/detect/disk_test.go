@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAvailableDiskSpaceMiB(t *testing.T) {
+	dir := t.TempDir()
+	free, err := AvailableDiskSpaceMiB(dir)
+	if err != nil {
+		t.Fatalf("AvailableDiskSpaceMiB(%q) error = %v", dir, err)
+	}
+	if free <= 0 {
+		t.Errorf("AvailableDiskSpaceMiB(%q) = %d, want a positive value", dir, free)
+	}
+
+	missing := filepath.Join(dir, "does", "not", "exist")
+	got, err := AvailableDiskSpaceMiB(missing)
+	if err != nil {
+		t.Fatalf("AvailableDiskSpaceMiB(%q) error = %v", missing, err)
+	}
+	if got <= 0 {
+		t.Errorf("AvailableDiskSpaceMiB(%q) = %d, want a positive value", missing, got)
+	}
+}
+
+func TestNearestExistingDir(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		want string
+	}{
+		{dir, dir},
+		{filepath.Join(dir, "missing"), dir},
+		{filepath.Join(dir, "missing", "deeper", "still"), dir},
+	}
+
+	for _, tc := range tests {
+		got, err := nearestExistingDir(tc.path)
+		if err != nil {
+			t.Fatalf("nearestExistingDir(%q) error = %v", tc.path, err)
+		}
+		if got != tc.want {
+			t.Errorf("nearestExistingDir(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestMinikubeHomeFreeSpaceMiB(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", filepath.Join(t.TempDir(), ".minikube"))
+	free, err := MinikubeHomeFreeSpaceMiB()
+	if err != nil {
+		t.Fatalf("MinikubeHomeFreeSpaceMiB() error = %v", err)
+	}
+	if free <= 0 {
+		t.Errorf("MinikubeHomeFreeSpaceMiB() = %d, want a positive value", free)
+	}
+}
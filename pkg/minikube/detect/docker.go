@@ -0,0 +1,191 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// dockerDaemonTimeout bounds how long DockerDaemonRunning waits for the docker daemon to answer
+	dockerDaemonTimeout = 3 * time.Second
+	// dockerInfoTimeout bounds how long the detectors reading "docker info" wait for the docker daemon
+	dockerInfoTimeout = 10 * time.Second
+	// dockerDaemonTTL is how long the outcome of DockerDaemonRunning is reused
+	dockerDaemonTTL = 5 * time.Second
+)
+
+// dockerDaemonCacheKey is the detect cache entry of DockerDaemonRunning
+const dockerDaemonCacheKey = "docker-daemon"
+
+// DockerDaemonRunning returns true if the active docker daemon answers within a few seconds.
+// The result is reused for a few seconds, so that repeated pre-flight checks do not each wait on the daemon.
+func DockerDaemonRunning() bool {
+	running, _ := cachedBool(dockerDaemonCacheKey, dockerDaemonTTL, func() (bool, error) {
+		_, err := dockerServerVersion(context.Background())
+		return err == nil, err
+	})
+	return running
+}
+
+// DockerDaemonRunningWithContext is DockerDaemonRunning, giving up when ctx is done.
+// The result is not cached, as a canceled check says nothing about the daemon.
+func DockerDaemonRunningWithContext(ctx context.Context) bool {
+	_, err := dockerServerVersion(ctx)
+	return err == nil
+}
+
+// DockerServerVersion returns the version of the active docker daemon, e.g. "20.10.21"
+func DockerServerVersion() (string, error) {
+	return dockerServerVersion(context.Background())
+}
+
+// DockerServerVersionWithContext is DockerServerVersion, giving up when ctx is done
+func DockerServerVersionWithContext(ctx context.Context) (string, error) {
+	return dockerServerVersion(ctx)
+}
+
+func dockerServerVersion(ctx context.Context) (string, error) {
+	o, err := runCommandTimeout(ctx, dockerDaemonTimeout, "docker", "version", "--format", "{{.Server.Version}}")
+	if err != nil {
+		// the docker CLI explains why the daemon is unreachable on stderr, e.g. "Cannot connect to the Docker daemon"
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("docker version: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("docker version: %w", err)
+	}
+	v := strings.TrimSpace(string(o))
+	if v == "" {
+		return "", fmt.Errorf("docker version: no server version reported")
+	}
+	return v, nil
+}
+
+var rootlessDockerProbe = memoizedProbe{"rootless-docker"}
+
+// IsRootlessDocker returns true if the active docker daemon runs in rootless mode.
+// The result is computed once per process.
+func IsRootlessDocker() bool {
+	rootless, _ := rootlessDockerProbe.get(background(isRootlessDocker))
+	return rootless
+}
+
+func isRootlessDocker(ctx context.Context) (bool, error) {
+	// rootless dockerd listens on a socket in the user's runtime dir
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && strings.HasPrefix(host, "unix://"+filepath.Join(dir, "docker.sock")) {
+			return true, nil
+		}
+	}
+
+	info, err := readDockerInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, o := range info.SecurityOptions {
+		if o == "name=rootless" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dockerInfo is the subset of "docker info" used by the docker detectors
+type dockerInfo struct {
+	Name            string
+	OperatingSystem string
+	OSType          string
+	KernelVersion   string
+	SecurityOptions []string
+	NCPU            int
+	MemTotal        int64
+}
+
+// readDockerInfo returns the parsed output of "docker info" for the active docker endpoint
+func readDockerInfo(ctx context.Context) (dockerInfo, error) {
+	var info dockerInfo
+	o, err := runCommandTimeout(ctx, dockerInfoTimeout, "docker", "info", "--format", "{{json .}}")
+	if err != nil {
+		return info, fmt.Errorf("docker info: %w", err)
+	}
+	if err := json.Unmarshal(o, &info); err != nil {
+		return info, fmt.Errorf("parsing docker info: %w", err)
+	}
+	return info, nil
+}
+
+var dockerDesktopProbe = memoizedProbe{"docker-desktop"}
+
+// IsDockerDesktop returns true if the active docker endpoint is Docker Desktop (including its WSL integration)
+// rather than a native Linux docker engine. The result is computed once per process.
+func IsDockerDesktop() bool {
+	desktop, _ := dockerDesktopProbe.get(background(isDockerDesktop))
+	return desktop
+}
+
+func isDockerDesktop(ctx context.Context) (bool, error) {
+	info, err := readDockerInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+	return isDockerDesktopInfo(info), nil
+}
+
+// dockerDesktopWarnings warns when the Docker Desktop VM of the active docker endpoint is too small for Kubernetes
+func dockerDesktopWarnings(ctx context.Context) []Warning {
+	info, err := readDockerInfo(ctx)
+	if err != nil || !isDockerDesktopInfo(info) {
+		return nil
+	}
+	const remediation = "raise the CPU and memory limits under Settings > Resources in Docker Desktop"
+	var ws []Warning
+	if mem := int(info.MemTotal / 1024 / 1024); info.MemTotal > 0 && mem < kubeadmMinMemoryMiB {
+		ws = append(ws, Warning{
+			Detector:    "docker-desktop",
+			Severity:    SeverityError,
+			Message:     fmt.Sprintf("Docker Desktop is limited to %dMiB of memory, less than the %dMiB Kubernetes needs", mem, kubeadmMinMemoryMiB),
+			Remediation: remediation,
+		})
+	}
+	if info.NCPU > 0 && info.NCPU < kubeadmMinCPUs {
+		ws = append(ws, Warning{
+			Detector:    "docker-desktop",
+			Severity:    SeverityError,
+			Message:     fmt.Sprintf("Docker Desktop is limited to %d CPU, less than the %d Kubernetes needs", info.NCPU, kubeadmMinCPUs),
+			Remediation: remediation,
+		})
+	}
+	return ws
+}
+
+// isDockerDesktopInfo returns true if info describes a Docker Desktop VM
+func isDockerDesktopInfo(info dockerInfo) bool {
+	if strings.Contains(info.OperatingSystem, "Docker Desktop") || info.Name == "docker-desktop" {
+		return true
+	}
+	// older releases report the LinuxKit VM only through the kernel version
+	return info.OSType == "linux" && strings.Contains(info.KernelVersion, "linuxkit")
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsRootlessDocker(t *testing.T) {
+	info := "docker info --format {{json .}}"
+	tests := []struct {
+		name       string
+		dockerHost string
+		outputs    map[string]string
+		want       bool
+	}{
+		{"rootful", "", map[string]string{info: `{"SecurityOptions":["name=apparmor","name=seccomp,profile=builtin"]}`}, false},
+		{"rootless info", "", map[string]string{info: `{"SecurityOptions":["name=seccomp,profile=builtin","name=rootless","name=cgroupns"]}`}, true},
+		{"rootless socket", "unix:///run/user/1000/docker.sock", map[string]string{}, true},
+		{"docker missing", "", map[string]string{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", tc.dockerHost)
+			t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+			fakeCommand(t, tc.outputs)
+			rootlessDockerProbe.reset()
+			defer rootlessDockerProbe.reset()
+			if got := IsRootlessDocker(); got != tc.want {
+				t.Errorf("IsRootlessDocker() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsDockerDesktop(t *testing.T) {
+	info := "docker info --format {{json .}}"
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		want    bool
+	}{
+		{"mac", map[string]string{info: `{"Name":"docker-desktop","OperatingSystem":"Docker Desktop","OSType":"linux","KernelVersion":"5.15.49-linuxkit"}`}, true},
+		{"wsl integration", map[string]string{info: `{"Name":"docker-desktop","OperatingSystem":"Docker Desktop","OSType":"linux","KernelVersion":"5.10.102.1-microsoft-standard-WSL2"}`}, true},
+		{"old linuxkit", map[string]string{info: `{"Name":"linuxkit-025000000001","OperatingSystem":"Alpine Linux v3.8","OSType":"linux","KernelVersion":"4.9.93-linuxkit-aufs"}`}, true},
+		{"native engine", map[string]string{info: `{"Name":"buildhost","OperatingSystem":"Ubuntu 22.04.1 LTS","OSType":"linux","KernelVersion":"5.15.0-1022-gcp"}`}, false},
+		{"invalid json", map[string]string{info: "Cannot connect to the Docker daemon"}, false},
+		{"docker missing", map[string]string{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeCommand(t, tc.outputs)
+			dockerDesktopProbe.reset()
+			defer dockerDesktopProbe.reset()
+			if got := IsDockerDesktop(); got != tc.want {
+				t.Errorf("IsDockerDesktop() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeCommandFailure makes runCommandContext fail for cmd as a program exiting with the given stderr, and succeed with the other outputs
+func fakeCommandFailure(t *testing.T, cmd, stderr string, outputs map[string]string) {
+	t.Helper()
+	fakeCommand(t, outputs)
+	stubbed := runCommandContext
+	runCommandContext = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		if strings.Join(append([]string{name}, arg...), " ") == cmd {
+			return nil, &exec.ExitError{ProcessState: &os.ProcessState{}, Stderr: []byte(stderr)}
+		}
+		return stubbed(ctx, name, arg...)
+	}
+}
+
+func TestDockerServerVersion(t *testing.T) {
+	version := "docker version --format {{.Server.Version}}"
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		stderr  string
+		want    string
+		wantErr string
+	}{
+		{"running", map[string]string{version: "20.10.21\n"}, "", "20.10.21", ""},
+		{"daemon down", nil, "Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?\n", "", "Cannot connect to the Docker daemon"},
+		{"no server", map[string]string{version: "\n"}, "", "", "no server version"},
+		{"docker missing", map[string]string{}, "", "", "not found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.stderr != "" {
+				fakeCommandFailure(t, version, tc.stderr, tc.outputs)
+			} else {
+				fakeCommand(t, tc.outputs)
+			}
+			got, err := DockerServerVersion()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("DockerServerVersion() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DockerServerVersion() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("DockerServerVersion() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDockerDaemonRunning(t *testing.T) {
+	version := "docker version --format {{.Server.Version}}"
+	resetDockerDaemonProbe := func() { forgetDetection(dockerDaemonCacheKey) }
+	defer resetDockerDaemonProbe()
+
+	resetDockerDaemonProbe()
+	fakeCommandFailure(t, version, "Cannot connect to the Docker daemon at unix:///var/run/docker.sock.", nil)
+	if DockerDaemonRunning() {
+		t.Error("DockerDaemonRunning() = true when the daemon cannot be reached")
+	}
+
+	// the outcome is reused until it expires
+	fakeCommand(t, map[string]string{version: "20.10.21\n"})
+	if DockerDaemonRunning() {
+		t.Error("DockerDaemonRunning() did not reuse its recent outcome")
+	}
+	resetDockerDaemonProbe()
+	if !DockerDaemonRunning() {
+		t.Error("DockerDaemonRunning() = false when the daemon answers")
+	}
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	orig := runCommandContext
+	t.Cleanup(func() { runCommandContext = orig })
+	runCommandContext = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if _, err := runCommandTimeout(context.Background(), 10*time.Millisecond, "docker", "version"); err == nil {
+		t.Error("runCommandTimeout() succeeded for a hung command")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := runCommandTimeout(ctx, time.Minute, "docker", "version")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runCommandTimeout() with a canceled context = %v, want %v", err, context.Canceled)
+	}
+}
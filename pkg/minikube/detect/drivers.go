@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// driverPreferences lists, per OS, the drivers DetectAvailableDrivers considers in order of preference.
+// The names match the ones of pkg/minikube/driver, which imports this package.
+var driverPreferences = map[string][]string{
+	"linux":   {"docker", "podman", "kvm2", "qemu2", "virtualbox", "vmware", "none", "ssh"},
+	"darwin":  {"docker", "vfkit", "qemu2", "hyperkit", "virtualbox", "vmware", "podman", "ssh"},
+	"windows": {"docker", "hyperv", "virtualbox", "vmware", "qemu2", "podman", "ssh"},
+}
+
+// driverProbes return whether each driver can plausibly be used on this host. It is a variable so that tests can stub it.
+var driverProbes = map[string]func(context.Context) bool{
+	"docker": DockerDaemonRunningWithContext,
+	"podman": func(ctx context.Context) bool {
+		_, err := PodmanVersionWithContext(ctx)
+		return err == nil
+	},
+	"kvm2":  func(context.Context) bool { return KVMAvailable() },
+	"qemu2": func(context.Context) bool { return QEMUInstalled() && hardwareVirtualization() },
+	"vfkit": func(context.Context) bool { return VfkitInstalled() && hardwareVirtualization() },
+	"hyperkit": func(context.Context) bool {
+		// hyperkit only runs on Intel Macs
+		return runtime.GOOS == "darwin" && EffectiveArch() == "amd64" && hasCommand("hyperkit") && hardwareVirtualization()
+	},
+	"hyperv":     func(context.Context) bool { return HyperVInstalled() },
+	"virtualbox": func(context.Context) bool { return VirtualBoxInstalled() && hardwareVirtualization() },
+	"vmware":     func(context.Context) bool { return VMwareInstalled() && hardwareVirtualization() },
+	"none": func(context.Context) bool {
+		return runtime.GOOS == "linux"
+	},
+	// any host can drive a remote machine
+	"ssh": func(context.Context) bool { return true },
+}
+
+// hardwareVirtualization returns false if the VM drivers cannot run accelerated VMs, as the host is an x86 CPU without
+// VT-x or AMD-V. HasVirtualizationExtensions cannot tell on other CPUs, which are assumed capable.
+// kvm2 and hyperv are not gated on it: /dev/kvm and a running Hyper-V already prove the extensions usable.
+// It is a variable so that tests can stub it.
+var hardwareVirtualization = func() bool {
+	return !isX86() || HasVirtualizationExtensions()
+}
+
+// socketVMNetInstalled is SocketVMNetInstalled. It is a variable so that tests can stub it.
+var socketVMNetInstalled = SocketVMNetInstalled
+
+// DetectAvailableDrivers returns the drivers minikube could use on this host, the preferred one first
+func DetectAvailableDrivers() []string {
+	return availableDrivers(context.Background(), runtime.GOOS)
+}
+
+// DetectAvailableDriversWithContext is DetectAvailableDrivers, skipping the remaining probes once ctx is done
+func DetectAvailableDriversWithContext(ctx context.Context) []string {
+	return availableDrivers(ctx, runtime.GOOS)
+}
+
+func availableDrivers(ctx context.Context, goos string) []string {
+	drivers := []string{}
+	for _, name := range driverPreference(goos) {
+		if ctx.Err() != nil {
+			break
+		}
+		if probe, ok := driverProbes[name]; ok && probe(ctx) {
+			drivers = append(drivers, name)
+		}
+	}
+	return drivers
+}
+
+// driverPreference returns the drivers to consider on goos in order of preference
+func driverPreference(goos string) []string {
+	names := append([]string{}, driverPreferences[goos]...)
+	// with socket_vmnet, qemu2 supports the networking features of vfkit and more, e.g. minikube tunnel
+	if goos == "darwin" && socketVMNetInstalled() {
+		names = moveBefore(names, "qemu2", "vfkit")
+	}
+	return names
+}
+
+// moveBefore moves name right before other in names, if it comes after it
+func moveBefore(names []string, name, other string) []string {
+	i, j := indexOf(names, name), indexOf(names, other)
+	if i < 0 || j < 0 || i < j {
+		return names
+	}
+	out := append([]string{}, names[:j]...)
+	out = append(out, name)
+	for k := j; k < len(names); k++ {
+		if k != i {
+			out = append(out, names[k])
+		}
+	}
+	return out
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasCommand returns true if the named executable is found in PATH
+func hasCommand(name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// toolVersionRe matches the first x.y.z version number printed by a tool, e.g. "v1.1.2" in "socket_vmnet version v1.1.2"
+var toolVersionRe = regexp.MustCompile(`\bv?(\d+\.\d+\.\d+)`)
+
+// parseToolVersion returns the version number, without a leading "v", from the output of tool's version command
+func parseToolVersion(tool, out string) (string, error) {
+	m := toolVersionRe.FindStringSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("unexpected %s version output: %q", tool, strings.TrimSpace(out))
+	}
+	return m[1], nil
+}
+
+// findBinary returns the path of the named executable in the first of dirs holding it, else in PATH
+func findBinary(name string, dirs ...string) (string, error) {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if p, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return p, nil
+		}
+	}
+	p, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s is not installed: %w", name, err)
+	}
+	return p, nil
+}
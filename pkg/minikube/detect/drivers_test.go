@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// fakeDriverProbes makes every driver probe report false, except for the available ones
+func fakeDriverProbes(t *testing.T, socketVMNet bool, available ...string) {
+	t.Helper()
+	origProbes, origSocketVMNet := driverProbes, socketVMNetInstalled
+	t.Cleanup(func() { driverProbes, socketVMNetInstalled = origProbes, origSocketVMNet })
+
+	driverProbes = map[string]func(context.Context) bool{}
+	for name := range origProbes {
+		driverProbes[name] = func(context.Context) bool { return false }
+	}
+	for _, name := range available {
+		driverProbes[name] = func(context.Context) bool { return true }
+	}
+	socketVMNetInstalled = func() bool { return socketVMNet }
+}
+
+func TestAvailableDrivers(t *testing.T) {
+	tests := []struct {
+		name        string
+		goos        string
+		available   []string
+		socketVMNet bool
+		want        []string
+	}{
+		{"linux", "linux", []string{"ssh", "none", "kvm2", "docker", "podman"}, false, []string{"docker", "podman", "kvm2", "none", "ssh"}},
+		{"linux without container runtime", "linux", []string{"ssh", "qemu2", "virtualbox"}, false, []string{"qemu2", "virtualbox", "ssh"}},
+		{"darwin", "darwin", []string{"ssh", "qemu2", "vfkit", "docker"}, false, []string{"docker", "vfkit", "qemu2", "ssh"}},
+		{"darwin with socket_vmnet", "darwin", []string{"ssh", "qemu2", "vfkit", "docker"}, true, []string{"docker", "qemu2", "vfkit", "ssh"}},
+		{"darwin with socket_vmnet and no vfkit", "darwin", []string{"ssh", "qemu2"}, true, []string{"qemu2", "ssh"}},
+		{"windows", "windows", []string{"ssh", "virtualbox", "hyperv", "docker"}, false, []string{"docker", "hyperv", "virtualbox", "ssh"}},
+		{"linux only drivers on windows", "windows", []string{"kvm2", "none", "ssh"}, false, []string{"ssh"}},
+		{"unknown os", "plan9", []string{"ssh", "docker"}, false, []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeDriverProbes(t, tc.socketVMNet, tc.available...)
+			if got := availableDrivers(context.Background(), tc.goos); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("availableDrivers(%q) = %v, want %v", tc.goos, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAvailableDriversCanceled(t *testing.T) {
+	fakeDriverProbes(t, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	probed := []string{}
+	driverProbes["docker"] = func(context.Context) bool {
+		probed = append(probed, "docker")
+		cancel()
+		return true
+	}
+	driverProbes["podman"] = func(context.Context) bool {
+		probed = append(probed, "podman")
+		return true
+	}
+	got := availableDrivers(ctx, "linux")
+	if want := []string{"docker"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("availableDrivers() = %v, want %v", got, want)
+	}
+	if want := []string{"docker"}; !reflect.DeepEqual(probed, want) {
+		t.Errorf("probed %v after the context was canceled, want only %v", probed, want)
+	}
+}
+
+func TestVMDriverProbesNeedVirtualization(t *testing.T) {
+	qemu, ok := qemuSystemBinaries[EffectiveArch()]
+	if !ok {
+		t.Skipf("no QEMU system emulator for %s", EffectiveArch())
+	}
+	fakeExecutables(t, qemu, "VBoxManage", "vmrun")
+	orig := hardwareVirtualization
+	t.Cleanup(func() { hardwareVirtualization = orig })
+
+	for _, virtualization := range []bool{true, false} {
+		virtualization := virtualization
+		hardwareVirtualization = func() bool { return virtualization }
+		for _, name := range []string{"qemu2", "virtualbox", "vmware"} {
+			if got := driverProbes[name](context.Background()); got != virtualization {
+				t.Errorf("%s probe = %t with the driver installed and virtualization extensions %t, want %t", name, got, virtualization, virtualization)
+			}
+		}
+	}
+}
+
+func TestDriverProbesCoverPreferences(t *testing.T) {
+	for goos, names := range driverPreferences {
+		for _, name := range names {
+			if _, ok := driverProbes[name]; !ok {
+				t.Errorf("driver %q preferred on %s has no probe", name, goos)
+			}
+		}
+	}
+}
+
+func TestMoveBefore(t *testing.T) {
+	tests := []struct {
+		names       []string
+		name, other string
+		want        []string
+	}{
+		{[]string{"a", "b", "c"}, "c", "a", []string{"c", "a", "b"}},
+		{[]string{"a", "b", "c"}, "c", "b", []string{"a", "c", "b"}},
+		{[]string{"a", "b", "c"}, "a", "c", []string{"a", "b", "c"}},
+		{[]string{"a", "b"}, "x", "a", []string{"a", "b"}},
+		{[]string{"a", "b"}, "b", "x", []string{"a", "b"}},
+	}
+
+	for _, tc := range tests {
+		if got := moveBefore(tc.names, tc.name, tc.other); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("moveBefore(%v, %q, %q) = %v, want %v", tc.names, tc.name, tc.other, got, tc.want)
+		}
+	}
+}
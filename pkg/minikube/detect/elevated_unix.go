@@ -0,0 +1,49 @@
+//go:build !windows
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"strconv"
+)
+
+// geteuid returns the effective user ID of minikube. It is a variable so that tests can stub it.
+var geteuid = os.Geteuid
+
+// IsElevated returns true if minikube runs as root, including under sudo
+func IsElevated() bool {
+	return geteuid() == 0
+}
+
+// EffectiveUIDGID returns the user and group IDs the files minikube creates should be owned by. Under sudo, these are
+// the IDs of the user who invoked sudo, read from SUDO_UID and SUDO_GID, so that the minikube home is not left owned
+// by root. Otherwise they are the real IDs of minikube.
+func EffectiveUIDGID() (uid int, gid int) {
+	uid, gid = os.Getuid(), os.Getgid()
+	// sudo sets both, a leftover environment of a regular user must not be trusted
+	if !IsElevated() {
+		return uid, gid
+	}
+	sudoUID, uerr := strconv.Atoi(os.Getenv("SUDO_UID"))
+	sudoGID, gerr := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if uerr != nil || gerr != nil || sudoUID < 0 || sudoGID < 0 {
+		return uid, gid
+	}
+	return sudoUID, sudoGID
+}
@@ -0,0 +1,75 @@
+//go:build !windows
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsElevated(t *testing.T) {
+	tests := []struct {
+		euid int
+		want bool
+	}{
+		{0, true},
+		{1000, false},
+		// os.Geteuid reports -1 where there are no user IDs
+		{-1, false},
+	}
+	orig := geteuid
+	defer func() { geteuid = orig }()
+	for _, tc := range tests {
+		geteuid = func() int { return tc.euid }
+		if got := IsElevated(); got != tc.want {
+			t.Errorf("IsElevated() with euid %d = %t, want %t", tc.euid, got, tc.want)
+		}
+	}
+}
+
+func TestEffectiveUIDGID(t *testing.T) {
+	uid, gid := os.Getuid(), os.Getgid()
+	tests := []struct {
+		name    string
+		euid    int
+		sudoUID string
+		sudoGID string
+		wantUID int
+		wantGID int
+	}{
+		{"sudo", 0, "1000", "1001", 1000, 1001},
+		{"root without sudo", 0, "", "", uid, gid},
+		{"leftover sudo environment", 1000, "1000", "1001", uid, gid},
+		{"missing SUDO_GID", 0, "1000", "", uid, gid},
+		{"invalid SUDO_UID", 0, "nobody", "1001", uid, gid},
+		{"negative SUDO_UID", 0, "-1", "1001", uid, gid},
+	}
+	orig := geteuid
+	defer func() { geteuid = orig }()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			geteuid = func() int { return tc.euid }
+			t.Setenv("SUDO_UID", tc.sudoUID)
+			t.Setenv("SUDO_GID", tc.sudoGID)
+			if u, g := EffectiveUIDGID(); u != tc.wantUID || g != tc.wantGID {
+				t.Errorf("EffectiveUIDGID() = %d, %d; want %d, %d", u, g, tc.wantUID, tc.wantGID)
+			}
+		})
+	}
+}
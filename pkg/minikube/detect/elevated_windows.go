@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "golang.org/x/sys/windows"
+
+// tokenElevated returns whether the access token of minikube is elevated. It is a variable so that tests can stub it.
+var tokenElevated = func() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// IsElevated returns true if minikube runs as an elevated administrator, i.e. "Run as administrator"
+// or an administrator account with UAC disabled. Members of Administrators running unelevated are not elevated.
+func IsElevated() bool {
+	return tokenElevated()
+}
+
+// EffectiveUIDGID returns the user and group IDs the files minikube creates should be owned by,
+// which is -1, -1 on windows where files have no numeric owner
+func EffectiveUIDGID() (uid int, gid int) {
+	return -1, -1
+}
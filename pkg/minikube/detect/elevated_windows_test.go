@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+func TestIsElevated(t *testing.T) {
+	orig := tokenElevated
+	defer func() { tokenElevated = orig }()
+	for _, elevated := range []bool{true, false} {
+		tokenElevated = func() bool { return elevated }
+		if got := IsElevated(); got != elevated {
+			t.Errorf("IsElevated() with an elevated token %t = %t", elevated, got)
+		}
+	}
+}
+
+func TestEffectiveUIDGID(t *testing.T) {
+	t.Setenv("SUDO_UID", "1000")
+	t.Setenv("SUDO_GID", "1000")
+	if uid, gid := EffectiveUIDGID(); uid != -1 || gid != -1 {
+		t.Errorf("EffectiveUIDGID() = %d, %d; want -1, -1", uid, gid)
+	}
+}
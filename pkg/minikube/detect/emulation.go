@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// IsAmd64WindowsARMEmulation determines whether an amd64 minikube binary is running under the x64 emulation of Windows on ARM
+func IsAmd64WindowsARMEmulation() bool {
+	return runtime.GOOS == "windows" && runtime.GOARCH == "amd64" && nativeMachineIsARM64()
+}
+
+// rosettaRuntime is installed by the Rosetta 2 package on Apple Silicon
+const rosettaRuntime = "/Library/Apple/usr/libexec/oah/libRosettaRuntime"
+
+// IsRosetta2Installed returns true if Rosetta 2 is available to run x86_64 binaries on macOS.
+// It returns false on other operating systems.
+func IsRosetta2Installed() bool {
+	if runtime.GOOS != "darwin" {
+		return false
+	}
+	return rosettaInstalled()
+}
+
+func rosettaInstalled() bool {
+	if fileExists(rosettaRuntime) {
+		return true
+	}
+	// arch fails with "Bad CPU type in executable" when Rosetta is missing
+	_, err := runCommand("arch", "-x86_64", "true")
+	return err == nil
+}
+
+// qemuBinfmtNames maps GOARCH values to the names qemu-user registers with binfmt_misc
+var qemuBinfmtNames = map[string]string{
+	"amd64":   "x86_64",
+	"386":     "i386",
+	"arm64":   "aarch64",
+	"arm":     "arm",
+	"riscv64": "riscv64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// IsRunningUnderQEMUUserEmulation returns true if the minikube binary is being executed by qemu-user
+// through binfmt_misc, as is common in multi-arch container builds. Such environments cannot use the kvm2 driver.
+// Only Linux is supported; other platforms return false.
+func IsRunningUnderQEMUUserEmulation() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return qemuUserEmulation(runtime.GOARCH)
+}
+
+func qemuUserEmulation(goarch string) bool {
+	name, ok := qemuBinfmtNames[goarch]
+	if !ok {
+		return false
+	}
+	b, err := os.ReadFile(hostPath("/proc/sys/fs/binfmt_misc/qemu-" + name))
+	if err != nil || !strings.HasPrefix(string(b), "enabled") {
+		return false
+	}
+
+	// qemu-user passes the host's cpuinfo through, so it describes a different architecture than the binary
+	host := cpuinfoArchFamily(readCPUInfo())
+	return host != "" && host != archFamily(goarch)
+}
+
+// archFamily returns the processor family of a GOARCH value
+func archFamily(goarch string) string {
+	switch goarch {
+	case "amd64", "386":
+		return "x86"
+	case "arm64", "arm":
+		return "arm"
+	case "riscv64":
+		return "riscv"
+	case "ppc64", "ppc64le":
+		return "ppc"
+	case "s390x":
+		return "s390"
+	}
+	return goarch
+}
+
+// cpuinfoArchFamily returns the processor family described by the contents of /proc/cpuinfo, or "" if unknown
+func cpuinfoArchFamily(cpuinfo string) string {
+	vendor := cpuinfoField(cpuinfo, "vendor_id")
+	switch {
+	case strings.HasPrefix(vendor, "IBM/S390"):
+		return "s390"
+	case vendor != "":
+		return "x86"
+	case cpuinfoField(cpuinfo, "CPU implementer") != "":
+		return "arm"
+	case strings.HasPrefix(cpuinfoField(cpuinfo, "isa"), "rv"):
+		return "riscv"
+	case strings.HasPrefix(cpuinfoField(cpuinfo, "cpu"), "POWER"):
+		return "ppc"
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+const (
+	x86CPUInfo = "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Intel(R) Xeon(R) CPU @ 2.20GHz\nflags\t\t: fpu vme de pse hypervisor\n"
+	armCPUInfo = "processor\t: 0\nBogoMIPS\t: 108.00\nFeatures\t: fp asimd evtstrm aes pmull sha1 sha2 crc32\nCPU implementer\t: 0x41\nCPU architecture: 8\n"
+)
+
+func TestQEMUUserEmulation(t *testing.T) {
+	binfmt := "enabled\ninterpreter /usr/bin/qemu-aarch64-static\nflags: F\noffset 0\n"
+	tests := []struct {
+		name   string
+		goarch string
+		files  map[string]string
+		want   bool
+	}{
+		{"arm64 on x86", "arm64", map[string]string{"/proc/sys/fs/binfmt_misc/qemu-aarch64": binfmt, "/proc/cpuinfo": x86CPUInfo}, true},
+		{"arm64 native", "arm64", map[string]string{"/proc/sys/fs/binfmt_misc/qemu-aarch64": binfmt, "/proc/cpuinfo": armCPUInfo}, false},
+		{"arm64 no binfmt", "arm64", map[string]string{"/proc/cpuinfo": x86CPUInfo}, false},
+		{"arm64 binfmt disabled", "arm64", map[string]string{"/proc/sys/fs/binfmt_misc/qemu-aarch64": "disabled\n", "/proc/cpuinfo": x86CPUInfo}, false},
+		{"amd64 on arm", "amd64", map[string]string{"/proc/sys/fs/binfmt_misc/qemu-x86_64": binfmt, "/proc/cpuinfo": armCPUInfo}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := qemuUserEmulation(tc.goarch); got != tc.want {
+				t.Errorf("qemuUserEmulation(%q) = %t, want %t", tc.goarch, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRosettaInstalled(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		commands map[string]string
+		want     bool
+	}{
+		{"runtime present", map[string]string{rosettaRuntime: ""}, nil, true},
+		{"arch succeeds", nil, map[string]string{"arch -x86_64 true": ""}, true},
+		{"not installed", nil, nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			fakeCommand(t, tc.commands)
+			if got := rosettaInstalled(); got != tc.want {
+				t.Errorf("rosettaInstalled() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"golang.org/x/sys/windows"
+	"k8s.io/klog/v2"
+)
+
+// imageFileMachineARM64 is IMAGE_FILE_MACHINE_ARM64 from winnt.h
+const imageFileMachineARM64 = 0xAA64
+
+// isWow64Process2 returns the machine types of the current process and of the native system.
+// It is a variable so that tests can stub the syscall.
+var isWow64Process2 = func() (process uint16, native uint16, err error) {
+	err = windows.IsWow64Process2(windows.CurrentProcess(), &process, &native)
+	return process, native, err
+}
+
+// nativeMachineIsARM64 returns true if Windows itself runs on ARM64, whatever the architecture of this process
+func nativeMachineIsARM64() bool {
+	_, native, err := isWow64Process2()
+	if err != nil {
+		// IsWow64Process2 is missing before Windows 10 1709, which predates x64 emulation on ARM
+		klog.Infof("unable to determine the native machine type: %v", err)
+		return false
+	}
+	return native == imageFileMachineARM64
+}
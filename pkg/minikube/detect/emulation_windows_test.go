@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNativeMachineIsARM64(t *testing.T) {
+	const (
+		imageFileMachineUnknown = 0
+		imageFileMachineAMD64   = 0x8664
+		imageFileMachineI386    = 0x14c
+	)
+	tests := []struct {
+		name    string
+		process uint16
+		native  uint16
+		err     error
+		want    bool
+	}{
+		{"x64 emulation on arm64", imageFileMachineUnknown, imageFileMachineARM64, nil, true},
+		{"x86 wow64 on arm64", imageFileMachineI386, imageFileMachineARM64, nil, true},
+		{"native x64", imageFileMachineUnknown, imageFileMachineAMD64, nil, false},
+		{"syscall unavailable", 0, 0, errors.New("procedure not found"), false},
+	}
+
+	orig := isWow64Process2
+	defer func() { isWow64Process2 = orig }()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isWow64Process2 = func() (uint16, uint16, error) { return tc.process, tc.native, tc.err }
+			if got := nativeMachineIsARM64(); got != tc.want {
+				t.Errorf("nativeMachineIsARM64() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
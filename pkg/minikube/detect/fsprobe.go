@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// caseProbePrefix names the probe files of IsCaseInsensitiveFilesystem, it must contain letters
+const caseProbePrefix = ".minikube-case-probe-"
+
+// IsCaseInsensitiveFilesystem returns true if the filesystem holding the directory path treats file names that
+// differ only in case as the same file, as the macOS and Windows defaults do. It creates and removes two probe
+// files in path, and returns an error if path is not writable.
+func IsCaseInsensitiveFilesystem(path string) (bool, error) {
+	lower, err := os.CreateTemp(path, caseProbePrefix+"*")
+	if err != nil {
+		return false, fmt.Errorf("creating a case probe file in %s: %w", path, err)
+	}
+	lower.Close()
+	defer os.Remove(lower.Name())
+
+	upperName := filepath.Join(filepath.Dir(lower.Name()), strings.ToUpper(filepath.Base(lower.Name())))
+	upper, err := os.OpenFile(upperName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("creating a case probe file in %s: %w", path, err)
+	}
+	upper.Close()
+	os.Remove(upperName)
+	return false, nil
+}
+
+// caseInsensitiveCacheWarnings warns when the cache is on a case-insensitive filesystem. A cache that does not exist yet
+// holds nothing to corrupt and is not probed.
+func caseInsensitiveCacheWarnings(context.Context) []Warning {
+	dir := CacheDir()
+	if insensitive, err := IsCaseInsensitiveFilesystem(dir); err != nil || !insensitive {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "case-insensitive-filesystem",
+		Severity:    SeverityInfo,
+		Message:     fmt.Sprintf("%s is on a case-insensitive filesystem, where images whose names differ only in case overwrite each other in the cache", dir),
+		Remediation: "avoid image names that differ only in case, or set MINIKUBE_HOME to a case-sensitive volume",
+	}}
+}
+
+// SupportsSymlinks returns true if a symlink can be created, read back and followed in the directory path.
+// Creating symlinks on Windows requires Developer Mode or an elevated process. The probe link is removed.
+func SupportsSymlinks(path string) bool {
+	target, err := os.CreateTemp(path, ".minikube-symlink-probe-*")
+	if err != nil {
+		klog.Infof("unable to probe for symlinks in %s: %v", path, err)
+		return false
+	}
+	target.Close()
+	defer os.Remove(target.Name())
+
+	rel := filepath.Base(target.Name())
+	link := target.Name() + ".link"
+	if err := os.Symlink(rel, link); err != nil {
+		klog.Infof("symlinks are not supported in %s: %v", path, err)
+		return false
+	}
+	defer os.Remove(link)
+
+	if got, err := os.Readlink(link); err != nil || got != rel {
+		klog.Infof("symlinks cannot be read back in %s: %q, %v", path, got, err)
+		return false
+	}
+	// some network filesystems store symlinks without following them
+	if _, err := os.Stat(link); err != nil {
+		klog.Infof("symlinks cannot be followed in %s: %v", path, err)
+		return false
+	}
+	return true
+}
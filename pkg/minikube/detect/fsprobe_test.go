@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+func TestIsCaseInsensitiveFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	probe := filepath.Join(dir, "Probe")
+	if err := os.WriteFile(probe, nil, 0600); err != nil {
+		t.Skipf("unable to create files in %s: %v", dir, err)
+	}
+	// the expected outcome is what the filesystem does with a name differing only in case
+	_, err := os.Stat(filepath.Join(dir, "pROBE"))
+	want := err == nil
+	os.Remove(probe)
+
+	got, err := IsCaseInsensitiveFilesystem(dir)
+	if err != nil {
+		t.Fatalf("IsCaseInsensitiveFilesystem(%s) returned unexpected error: %v", dir, err)
+	}
+	if got != want {
+		t.Errorf("IsCaseInsensitiveFilesystem(%s) = %t, want %t", dir, got, want)
+	}
+
+	// the probe files are cleaned up
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", dir, err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(strings.ToLower(e.Name()), caseProbePrefix) {
+			t.Errorf("IsCaseInsensitiveFilesystem(%s) left %s behind", dir, e.Name())
+		}
+	}
+
+	if _, err := IsCaseInsensitiveFilesystem(filepath.Join(dir, "missing")); err == nil {
+		t.Error("IsCaseInsensitiveFilesystem() succeeded for a missing directory")
+	}
+}
+
+func TestIsCaseInsensitiveFilesystemReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory modes do not restrict access on windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses file permissions")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatalf("failed to make %s read-only: %v", dir, err)
+	}
+
+	if _, err := IsCaseInsensitiveFilesystem(dir); err == nil {
+		t.Errorf("IsCaseInsensitiveFilesystem(%s) succeeded in a read-only directory", dir)
+	}
+}
+
+func TestCaseInsensitiveCacheWarnings(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(constants.MinikubeCacheDirEnv, dir)
+	insensitive, err := IsCaseInsensitiveFilesystem(dir)
+	if err != nil {
+		t.Skipf("unable to probe %s: %v", dir, err)
+	}
+
+	ws := caseInsensitiveCacheWarnings(context.Background())
+	if !insensitive && len(ws) != 0 {
+		t.Errorf("caseInsensitiveCacheWarnings() = %+v for a case-sensitive cache, want none", ws)
+	}
+	if insensitive && (len(ws) != 1 || !strings.Contains(ws[0].Message, dir)) {
+		t.Errorf("caseInsensitiveCacheWarnings() = %+v for a case-insensitive cache, want a warning naming %s", ws, dir)
+	}
+}
+
+func TestSupportsSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Symlink("target", filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks are not supported in %s: %v", dir, err)
+	}
+	os.Remove(filepath.Join(dir, "link"))
+
+	if !SupportsSymlinks(dir) {
+		t.Errorf("SupportsSymlinks(%s) = false, want true", dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", dir, err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("SupportsSymlinks(%s) left %d probe files behind", dir, len(entries))
+	}
+
+	if SupportsSymlinks(filepath.Join(dir, "missing")) {
+		t.Error("SupportsSymlinks() = true for a missing directory")
+	}
+}
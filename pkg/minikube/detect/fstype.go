@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+// filesystemMagics maps the f_type reported by statfs on Linux to the name of the filesystem, see statfs(2)
+var filesystemMagics = map[uint32]string{
+	0x0000EF53: "ext4", // shared by ext2 and ext3
+	0x00006969: "nfs",
+	0x00004d44: "vfat",
+	0x0000f15f: "ecryptfs",
+	0x00c36400: "ceph",
+	0x01021994: "tmpfs",
+	0x01021997: "9p",
+	0x2011BAB0: "exfat",
+	0x2FC12FC1: "zfs",
+	0x5346544e: "ntfs",
+	0x58465342: "xfs",
+	0x61756673: "aufs",
+	0x65735546: "fuse",
+	0x73717368: "squashfs",
+	0x786f4256: "vboxsf",
+	0x794c7630: "overlayfs",
+	0x858458f6: "ramfs",
+	0x9123683E: "btrfs",
+	0xFE534D42: "smb2",
+	0xFF534D42: "cifs",
+}
+
+// filesystemTypeName returns the name of the Linux filesystem with the given magic number, or the number in hex
+func filesystemTypeName(magic uint32) string {
+	if name, ok := filesystemMagics[magic]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", magic)
+}
+
+// unsuitableHomeFilesystems are the filesystems known to break file locking or the permissions of the minikube home
+var unsuitableHomeFilesystems = map[string]bool{
+	"overlayfs": true,
+	"nfs":       true,
+	"cifs":      true,
+	"smb2":      true,
+	"smbfs":     true,
+}
+
+// HomeFilesystemType returns the type of the filesystem holding the minikube home directory, e.g. "ext4",
+// "apfs" or "ntfs". Linux filesystems without a known name are reported by their magic number in hex.
+func HomeFilesystemType() (string, error) {
+	_, fs, err := homeFilesystem()
+	return fs, err
+}
+
+// homeFilesystem returns the nearest existing directory of the minikube home and the type of its filesystem
+func homeFilesystem() (dir, fs string, err error) {
+	dir, err = nearestExistingDir(localpath.MiniPath())
+	if err != nil {
+		return "", "", err
+	}
+	fs, err = filesystemType(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("checking the filesystem of %s: %w", dir, err)
+	}
+	return dir, fs, nil
+}
+
+// homeFilesystemWarnings warns when the minikube home is on a filesystem known to break file locking or permissions
+func homeFilesystemWarnings(context.Context) []Warning {
+	dir, fs, err := homeFilesystem()
+	if err != nil || !unsuitableHomeFilesystems[fs] {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "home-filesystem",
+		Severity:    SeverityWarning,
+		Message:     fmt.Sprintf("the minikube home %s is on %s, where file locking and permissions may not work as minikube expects", dir, fs),
+		Remediation: "set MINIKUBE_HOME to a directory on a local disk",
+	}}
+}
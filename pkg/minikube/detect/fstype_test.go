@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+func TestFilesystemTypeName(t *testing.T) {
+	tests := []struct {
+		magic uint32
+		want  string
+	}{
+		{0xEF53, "ext4"},
+		{0x794c7630, "overlayfs"},
+		{0x6969, "nfs"},
+		{0x01021994, "tmpfs"},
+		{0x9123683E, "btrfs"},
+		{0xFF534D42, "cifs"},
+		{0x12345678, "0x12345678"},
+	}
+	for _, tc := range tests {
+		if got := filesystemTypeName(tc.magic); got != tc.want {
+			t.Errorf("filesystemTypeName(0x%x) = %q, want %q", tc.magic, got, tc.want)
+		}
+	}
+}
+
+func TestHomeFilesystemType(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "windows":
+	default:
+		t.Skipf("filesystem type detection is not implemented on %s", runtime.GOOS)
+	}
+	// the home does not need to exist yet
+	t.Setenv(localpath.MinikubeHome, filepath.Join(t.TempDir(), "missing"))
+	fs, err := HomeFilesystemType()
+	if err != nil {
+		t.Fatalf("HomeFilesystemType() returned unexpected error: %v", err)
+	}
+	if fs == "" {
+		t.Error("HomeFilesystemType() = \"\", want a filesystem type")
+	}
+}
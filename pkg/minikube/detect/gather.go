@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Detect is a snapshot of everything minikube detects about the host it runs on
+type Detect struct {
+	OS                       string
+	Arch                     string
+	EffectiveArch            string
+	CloudProvider            Provider
+	CI                       string
+	InContainer              bool
+	Hypervisor               string
+	VirtualizationExtensions bool
+	InstallMethod            string
+	TotalMemoryMiB           int
+	AvailableMemoryMiB       int
+	CPUs                     int
+	Drivers                  []string
+	// Unavailable maps a capability, a driver or a failed detector, to the reason it is unavailable
+	Unavailable map[string]string
+}
+
+// DetectSchemaVersion is the version of the JSON encoding of Detect, bumped whenever a field changes incompatibly
+const DetectSchemaVersion = 1
+
+// unavailable records why the named capability is unavailable
+func (d *Detect) unavailable(name, reason string) {
+	if d.Unavailable == nil {
+		d.Unavailable = map[string]string{}
+	}
+	d.Unavailable[name] = reason
+}
+
+// gatherer fills in some fields of a Detect. gather returns a function applying its findings,
+// so that results arriving after the deadline can be dropped without racing with the caller.
+type gatherer struct {
+	name   string
+	gather func(ctx context.Context) (func(d *Detect), error)
+}
+
+// gatherers are the detectors Gather runs in parallel. It is a variable so that tests can stub them.
+var gatherers = []gatherer{
+	{"os", func(context.Context) (func(*Detect), error) {
+		goos, arch, effective := RuntimeOS(), RuntimeArch(), EffectiveArch()
+		return func(d *Detect) { d.OS, d.Arch, d.EffectiveArch = goos, arch, effective }, nil
+	}},
+	{"cloud", func(ctx context.Context) (func(*Detect), error) {
+		p := CloudProviderWithContext(ctx)
+		return func(d *Detect) { d.CloudProvider = p }, nil
+	}},
+	{"ci", func(context.Context) (func(*Detect), error) {
+		ci := CIName()
+		return func(d *Detect) { d.CI = ci }, nil
+	}},
+	{"container", func(context.Context) (func(*Detect), error) {
+		in := IsInContainer()
+		return func(d *Detect) { d.InContainer = in }, nil
+	}},
+	{"virtualization", func(context.Context) (func(*Detect), error) {
+		h, ext := DetectHypervisor(), HasVirtualizationExtensions()
+		return func(d *Detect) { d.Hypervisor, d.VirtualizationExtensions = h, ext }, nil
+	}},
+	{"install", func(context.Context) (func(*Detect), error) {
+		m := InstallMethod()
+		return func(d *Detect) { d.InstallMethod = m }, nil
+	}},
+	{"memory", func(context.Context) (func(*Detect), error) {
+		total, err := TotalMemoryMiB()
+		if err != nil {
+			return nil, err
+		}
+		available, err := AvailableMemoryMiB()
+		if err != nil {
+			return nil, err
+		}
+		return func(d *Detect) { d.TotalMemoryMiB, d.AvailableMemoryMiB = total, available }, nil
+	}},
+	{"cpu", func(context.Context) (func(*Detect), error) {
+		n := LogicalCPUCount()
+		return func(d *Detect) { d.CPUs = n }, nil
+	}},
+	{"drivers", func(ctx context.Context) (func(*Detect), error) {
+		drivers := DetectAvailableDriversWithContext(ctx)
+		// kvm2 is the only driver whose probe tells why it is unusable
+		kvm, kvmReason := KVMStatus()
+		return func(d *Detect) {
+			d.Drivers = drivers
+			if runtime.GOOS == "linux" && !kvm {
+				d.unavailable("kvm2", kvmReason)
+			}
+		}, nil
+	}},
+}
+
+// gatherDeadline bounds how long Gather waits for all detectors, unless ctx expires earlier
+var gatherDeadline = 10 * time.Second
+
+// Gather runs all detectors in parallel and returns their findings. A detector failing leaves its fields unset.
+// If ctx expires before every detector completed, the partial findings are returned along with an error
+// naming the detectors that did not complete.
+func Gather(ctx context.Context) (*Detect, error) {
+	ctx, cancel := context.WithTimeout(ctx, gatherDeadline)
+	defer cancel()
+
+	type result struct {
+		name  string
+		apply func(*Detect)
+		err   error
+	}
+	// buffered so that detectors still running after the deadline do not leak blocked goroutines
+	results := make(chan result, len(gatherers))
+	for _, g := range gatherers {
+		go func(g gatherer) {
+			apply, err := g.gather(ctx)
+			results <- result{g.name, apply, err}
+		}(g)
+	}
+
+	d := &Detect{}
+	pending := map[string]bool{}
+	for _, g := range gatherers {
+		pending[g.name] = true
+	}
+	for range gatherers {
+		select {
+		case r := <-results:
+			delete(pending, r.name)
+			if r.err != nil {
+				klog.Warningf("%s detection failed: %v", r.name, r.err)
+				d.unavailable(r.name, r.err.Error())
+				continue
+			}
+			if r.apply != nil {
+				r.apply(d)
+			}
+		case <-ctx.Done():
+			names := make([]string, 0, len(pending))
+			for name := range pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				d.unavailable(name, "detection did not complete in time")
+			}
+			return d, fmt.Errorf("detection of %s did not complete: %w", strings.Join(names, ", "), ctx.Err())
+		}
+	}
+	return d, nil
+}
+
+// Report returns a human-readable summary of d
+func (d *Detect) Report() string {
+	arch := d.Arch
+	// Arch carries the ARM variant (arm/v7) that EffectiveArch, a GOARCH, does not
+	if base, _, _ := strings.Cut(d.Arch, "/"); d.EffectiveArch != "" && d.EffectiveArch != base {
+		arch = fmt.Sprintf("%s (emulated on %s)", d.Arch, d.EffectiveArch)
+	}
+	rows := [][2]string{
+		{"OS", d.OS},
+		{"Architecture", arch},
+		{"Cloud provider", orNone(string(d.CloudProvider))},
+		{"CI", orNone(d.CI)},
+		{"In container", yesNo(d.InContainer)},
+		{"Hypervisor", orNone(d.Hypervisor)},
+		{"Virtualization", yesNo(d.VirtualizationExtensions)},
+		{"Install method", orNone(d.InstallMethod)},
+		{"Memory", fmt.Sprintf("%d MiB available of %d MiB", d.AvailableMemoryMiB, d.TotalMemoryMiB)},
+		{"CPUs", fmt.Sprintf("%d", d.CPUs)},
+		{"Drivers", orNone(strings.Join(d.Drivers, ", "))},
+	}
+	var b strings.Builder
+	for _, r := range rows {
+		fmt.Fprintf(&b, "%-16s%s\n", r[0]+":", r[1])
+	}
+	if len(d.Unavailable) > 0 {
+		b.WriteString("Unavailable:\n")
+		names := make([]string, 0, len(d.Unavailable))
+		for name := range d.Unavailable {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "  %s: %s\n", name, d.Unavailable[name])
+		}
+	}
+	return b.String()
+}
+
+// detectJSON is the JSON encoding of Detect. Its field names are part of the output of "minikube detect -o json",
+// so they must only change along with DetectSchemaVersion.
+type detectJSON struct {
+	SchemaVersion            int               `json:"schemaVersion"`
+	OS                       string            `json:"os"`
+	Arch                     string            `json:"arch"`
+	EffectiveArch            string            `json:"effectiveArch"`
+	CloudProvider            string            `json:"cloudProvider"`
+	CI                       string            `json:"ci"`
+	InContainer              bool              `json:"inContainer"`
+	Hypervisor               string            `json:"hypervisor"`
+	VirtualizationExtensions bool              `json:"virtualizationExtensions"`
+	InstallMethod            string            `json:"installMethod"`
+	Memory                   memoryJSON        `json:"memory"`
+	CPUs                     int               `json:"cpus"`
+	Drivers                  []string          `json:"drivers"`
+	Unavailable              map[string]string `json:"unavailable"`
+}
+
+type memoryJSON struct {
+	TotalMiB     int `json:"totalMiB"`
+	AvailableMiB int `json:"availableMiB"`
+}
+
+// MarshalJSON encodes d with stable field names and a schema version. Empty lists and maps are encoded
+// as [] and {} rather than null, so that the output only depends on the findings.
+func (d Detect) MarshalJSON() ([]byte, error) {
+	j := detectJSON{
+		SchemaVersion:            DetectSchemaVersion,
+		OS:                       d.OS,
+		Arch:                     d.Arch,
+		EffectiveArch:            d.EffectiveArch,
+		CloudProvider:            string(d.CloudProvider),
+		CI:                       d.CI,
+		InContainer:              d.InContainer,
+		Hypervisor:               d.Hypervisor,
+		VirtualizationExtensions: d.VirtualizationExtensions,
+		InstallMethod:            d.InstallMethod,
+		Memory:                   memoryJSON{TotalMiB: d.TotalMemoryMiB, AvailableMiB: d.AvailableMemoryMiB},
+		CPUs:                     d.CPUs,
+		Drivers:                  d.Drivers,
+		Unavailable:              d.Unavailable,
+	}
+	if j.Drivers == nil {
+		j.Drivers = []string{}
+	}
+	if j.Unavailable == nil {
+		j.Unavailable = map[string]string{}
+	}
+	// encoding/json sorts map keys, which keeps the output deterministic
+	return json.Marshal(j)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeGatherers replaces the detectors run by Gather
+func fakeGatherers(t *testing.T, gs ...gatherer) {
+	t.Helper()
+	orig := gatherers
+	gatherers = gs
+	t.Cleanup(func() { gatherers = orig })
+}
+
+// fakeHost returns gatherers reporting a fixed linux host
+func fakeHost() []gatherer {
+	return []gatherer{
+		{"os", func(context.Context) (func(*Detect), error) {
+			return func(d *Detect) { d.OS, d.Arch, d.EffectiveArch = "linux", "amd64", "amd64" }, nil
+		}},
+		{"cloud", func(context.Context) (func(*Detect), error) {
+			return func(d *Detect) { d.CloudProvider = ProviderGCE }, nil
+		}},
+		{"memory", func(context.Context) (func(*Detect), error) {
+			return func(d *Detect) { d.TotalMemoryMiB, d.AvailableMemoryMiB = 16384, 8192 }, nil
+		}},
+		{"cpu", func(context.Context) (func(*Detect), error) {
+			return func(d *Detect) { d.CPUs = 8 }, nil
+		}},
+		{"drivers", func(context.Context) (func(*Detect), error) {
+			return func(d *Detect) { d.Drivers = []string{"docker", "kvm2", "ssh"} }, nil
+		}},
+	}
+}
+
+func TestGather(t *testing.T) {
+	failing := gatherer{"install", func(context.Context) (func(*Detect), error) {
+		return nil, errors.New("broken")
+	}}
+	fakeGatherers(t, append(fakeHost(), failing)...)
+
+	got, err := Gather(context.Background())
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	want := &Detect{
+		OS:                 "linux",
+		Arch:               "amd64",
+		EffectiveArch:      "amd64",
+		CloudProvider:      ProviderGCE,
+		TotalMemoryMiB:     16384,
+		AvailableMemoryMiB: 8192,
+		CPUs:               8,
+		Drivers:            []string{"docker", "kvm2", "ssh"},
+		Unavailable:        map[string]string{"install": "broken"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Gather() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGatherDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := gatherer{"cloud", func(context.Context) (func(*Detect), error) {
+		// ignores ctx, like a probe stuck in a syscall
+		<-release
+		return func(d *Detect) { d.CloudProvider = ProviderAWS }, nil
+	}}
+	fast := gatherer{"cpu", func(context.Context) (func(*Detect), error) {
+		return func(d *Detect) { d.CPUs = 4 }, nil
+	}}
+	fakeGatherers(t, slow, fast)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	got, err := Gather(ctx)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Gather() took %s despite its deadline", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "cloud") {
+		t.Errorf("Gather() error = %v, want a deadline error naming the cloud detector", err)
+	}
+	if got.CPUs != 4 || got.CloudProvider != ProviderNone {
+		t.Errorf("Gather() = %+v, want the findings of the completed detectors only", got)
+	}
+	if _, ok := got.Unavailable["cloud"]; !ok {
+		t.Errorf("Gather() = %+v, want the cloud detector reported unavailable", got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	d := &Detect{
+		OS:                       "darwin",
+		Arch:                     "amd64",
+		EffectiveArch:            "arm64",
+		InstallMethod:            "homebrew",
+		VirtualizationExtensions: true,
+		TotalMemoryMiB:           16384,
+		AvailableMemoryMiB:       8192,
+		CPUs:                     10,
+		Drivers:                  []string{"docker", "qemu2"},
+		Unavailable:              map[string]string{"vfkit": "vfkit is not installed", "memory": "vm_stat failed"},
+	}
+	want := `OS:             darwin
+Architecture:   amd64 (emulated on arm64)
+Cloud provider: none
+CI:             none
+In container:   no
+Hypervisor:     none
+Virtualization: yes
+Install method: homebrew
+Memory:         8192 MiB available of 16384 MiB
+CPUs:           10
+Drivers:        docker, qemu2
+Unavailable:
+  memory: vm_stat failed
+  vfkit: vfkit is not installed
+`
+	if got := d.Report(); got != want {
+		t.Errorf("Report() =\n%s\nwant\n%s", got, want)
+	}
+
+	// a native 32-bit ARM host reports its variant in Arch only
+	arm := &Detect{OS: "linux", Arch: "arm/v7", EffectiveArch: "arm"}
+	if got := arm.Report(); !strings.Contains(got, "Architecture:   arm/v7\n") {
+		t.Errorf("Report() =\n%s\nwant Architecture: arm/v7 without emulation", got)
+	}
+}
+
+func TestDetectJSON(t *testing.T) {
+	d := Detect{
+		OS:                       "linux",
+		Arch:                     "amd64",
+		EffectiveArch:            "amd64",
+		CloudProvider:            ProviderGCE,
+		CI:                       "github",
+		VirtualizationExtensions: true,
+		InstallMethod:            "deb",
+		TotalMemoryMiB:           16384,
+		AvailableMemoryMiB:       8192,
+		CPUs:                     8,
+		Drivers:                  []string{"docker", "podman", "ssh"},
+		Unavailable: map[string]string{
+			"kvm2":   "/dev/kvm is not readable and writable by the current user: add the user to the group owning it, usually kvm or libvirt",
+			"memory": "unable to read MemAvailable from /proc/meminfo",
+		},
+	}
+	got, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent() error = %v", err)
+	}
+	want, err := os.ReadFile("testdata/detect.json")
+	if err != nil {
+		t.Fatalf("unable to read testdata: %v", err)
+	}
+	if diff := cmp.Diff(strings.TrimSpace(string(want)), string(got)); diff != "" {
+		t.Errorf("JSON encoding mismatch (-want +got):\n%s", diff)
+	}
+
+	// pointers encode the same, and empty findings encode as [] and {} rather than null
+	empty, err := json.Marshal(&Detect{})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, s := range []string{`"schemaVersion":1`, `"drivers":[]`, `"unavailable":{}`} {
+		if !strings.Contains(string(empty), s) {
+			t.Errorf("Marshal(&Detect{}) = %s, want it to contain %s", empty, s)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+// HyperVInstalled returns true if the Hyper-V feature is enabled, which is only possible on windows
+func HyperVInstalled() bool {
+	return false
+}
+
+// HyperVCanManage returns true if minikube may create Hyper-V VMs, which is only possible on windows
+func HyperVCanManage() bool {
+	return false
+}
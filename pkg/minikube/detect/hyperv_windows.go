@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"k8s.io/klog/v2"
+)
+
+// hyperVModuleQuery lists the Hyper-V PowerShell module, which the hyperv driver drives VMs with
+const hyperVModuleQuery = "@(Get-Module -ListAvailable hyper-v).Name | Get-Unique"
+
+// powershellTimeout bounds PowerShell queries, as its startup can be slow on loaded hosts
+const powershellTimeout = 8 * time.Second
+
+// inHyperVAdministrators returns whether the current user is in the Hyper-V Administrators group.
+// It is a variable so that tests can stub it.
+var inHyperVAdministrators = func() (bool, error) {
+	sid, err := windows.CreateWellKnownSid(windows.WinBuiltinHyperVAdminsSid)
+	if err != nil {
+		return false, err
+	}
+	// the zero token checks the membership of the user the current thread runs as
+	return windows.Token(0).IsMember(sid)
+}
+
+// HyperVInstalled returns true if the Hyper-V feature is enabled, as shown by the presence of its PowerShell module
+func HyperVInstalled() bool {
+	o, err := runCommandTimeout(context.Background(), powershellTimeout, "powershell", "-NoProfile", "-NonInteractive", hyperVModuleQuery)
+	if err != nil {
+		klog.Infof("unable to list the Hyper-V PowerShell module: %v", err)
+		return false
+	}
+	return strings.TrimSpace(string(o)) == "Hyper-V"
+}
+
+// HyperVCanManage returns true if minikube may create Hyper-V VMs, which requires either running elevated
+// or the current user being a member of the Hyper-V Administrators group
+func HyperVCanManage() bool {
+	if IsElevated() {
+		return true
+	}
+	member, err := inHyperVAdministrators()
+	if err != nil {
+		klog.Infof("unable to check Hyper-V Administrators membership: %v", err)
+		return false
+	}
+	return member
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHyperVInstalled(t *testing.T) {
+	query := "powershell -NoProfile -NonInteractive " + hyperVModuleQuery
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		want    bool
+	}{
+		{"enabled", map[string]string{query: "Hyper-V\r\n"}, true},
+		{"disabled", map[string]string{query: "\r\n"}, false},
+		{"no powershell", map[string]string{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeCommand(t, tc.outputs)
+			if got := HyperVInstalled(); got != tc.want {
+				t.Errorf("HyperVInstalled() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHyperVCanManage(t *testing.T) {
+	tests := []struct {
+		name     string
+		elevated bool
+		member   bool
+		err      error
+		want     bool
+	}{
+		{"elevated", true, false, nil, true},
+		{"hyper-v administrator", false, true, nil, true},
+		{"regular user", false, false, nil, false},
+		{"membership unknown", false, false, errors.New("access denied"), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			origElevated, origMember := tokenElevated, inHyperVAdministrators
+			t.Cleanup(func() { tokenElevated, inHyperVAdministrators = origElevated, origMember })
+			tokenElevated = func() bool { return tc.elevated }
+			inHyperVAdministrators = func() (bool, error) { return tc.member, tc.err }
+
+			if got := HyperVCanManage(); got != tc.want {
+				t.Errorf("HyperVCanManage() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
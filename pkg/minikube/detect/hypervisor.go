@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Hypervisors reported by DetectHypervisor
+const (
+	HypervisorKVM        = "kvm"
+	HypervisorVMware     = "vmware"
+	HypervisorVirtualBox = "virtualbox"
+	HypervisorHyperV     = "hyperv"
+	HypervisorXen        = "xen"
+	HypervisorQEMU       = "qemu"
+	HypervisorParallels  = "parallels"
+	// HypervisorUnknown means the host is virtualized but the platform could not be identified
+	HypervisorUnknown = "unknown"
+)
+
+// DetectHypervisor returns the virtualization platform the host minikube runs on is a guest of,
+// such as "kvm" or "vmware", or "" if the host appears to be bare metal.
+// The result is computed once per process.
+func DetectHypervisor() string {
+	return cachedString("hypervisor", 0, detectHypervisor)
+}
+
+// hypervisorFromCPUID identifies the hypervisor from the vendor signature in CPUID leaf 0x40000000.
+// The leaf is only defined when CPUID.1:ECX[31], the hypervisor present bit, is set.
+// Windows hosts running Hyper-V, WSL2 or virtualization-based security are themselves the root partition of
+// Hyper-V and see its signature too; they are bare metal.
+func hypervisorFromCPUID() string {
+	if _, _, ecx, _ := readCPUID(1, 0); ecx&(1<<31) == 0 {
+		return ""
+	}
+	maxLeaf, ebx, ecx, edx := readCPUID(0x40000000, 0)
+	sig := make([]byte, 0, 12)
+	for _, r := range []uint32{ebx, ecx, edx} {
+		sig = append(sig, byte(r), byte(r>>8), byte(r>>16), byte(r>>24))
+	}
+	h := hypervisorFromSignature(string(sig))
+	if h == HypervisorHyperV && maxLeaf >= 0x40000003 && hyperVRootPartition() {
+		return ""
+	}
+	return h
+}
+
+// hyperVRootPartition returns true if the CreatePartitions privilege, CPUID.40000003H:EBX[0], is set,
+// which Hyper-V only grants to the root partition
+func hyperVRootPartition() bool {
+	_, ebx, _, _ := readCPUID(0x40000003, 0)
+	return ebx&1 != 0
+}
+
+// hypervisorFromSignature maps a CPUID hypervisor vendor signature to a hypervisor name
+func hypervisorFromSignature(sig string) string {
+	switch strings.TrimRight(sig, "\x00") {
+	case "KVMKVMKVM":
+		return HypervisorKVM
+	case "Microsoft Hv":
+		return HypervisorHyperV
+	case "VMwareVMware":
+		return HypervisorVMware
+	case "XenVMMXenVMM":
+		return HypervisorXen
+	case "TCGTCGTCGTCG":
+		return HypervisorQEMU
+	case " lrpepyh  vr", "prl hyperv  ":
+		return HypervisorParallels
+	case "VBoxVBoxVBox":
+		return HypervisorVirtualBox
+	}
+	return HypervisorUnknown
+}
+
+// hypervisorFromDMI maps the firmware system vendor and product name to a hypervisor name
+func hypervisorFromDMI(vendor, product string) string {
+	vendor = strings.ToLower(vendor)
+	product = strings.ToLower(product)
+	switch {
+	case strings.Contains(product, "virtualbox") || strings.Contains(vendor, "innotek"):
+		return HypervisorVirtualBox
+	case strings.Contains(product, "vmware") || strings.Contains(vendor, "vmware"):
+		return HypervisorVMware
+	case strings.Contains(vendor, "microsoft") && strings.Contains(product, "virtual machine"):
+		return HypervisorHyperV
+	case strings.Contains(product, "hvm domu") || strings.Contains(vendor, "xen"):
+		return HypervisorXen
+	case strings.Contains(product, "kvm") || strings.Contains(vendor, "kvm"):
+		return HypervisorKVM
+	case strings.Contains(vendor, "qemu"):
+		return HypervisorQEMU
+	case strings.Contains(vendor, "parallels") || strings.Contains(product, "parallels"):
+		return HypervisorParallels
+	}
+	return ""
+}
+
+// hypervisorFromSysfs identifies the hypervisor of a Linux host from sysfs, procfs and CPUID
+func hypervisorFromSysfs() string {
+	if b, err := os.ReadFile(hostPath("/sys/hypervisor/type")); err == nil && strings.TrimSpace(string(b)) == "xen" {
+		return HypervisorXen
+	}
+
+	vendor, _ := os.ReadFile(hostPath("/sys/class/dmi/id/sys_vendor"))
+	product, _ := os.ReadFile(hostPath("/sys/class/dmi/id/product_name"))
+	if h := hypervisorFromDMI(string(vendor), string(product)); h != "" {
+		return h
+	}
+
+	if h := hypervisorFromCPUID(); h != "" {
+		return h
+	}
+	// the kernel exposes the CPUID hypervisor bit as the "hypervisor" flag
+	if cpuinfoHasFlag(readCPUInfo(), "hypervisor") {
+		return HypervisorUnknown
+	}
+	return ""
+}
+
+// HasVirtualizationExtensions returns true if the host CPU exposes hardware virtualization extensions,
+// VT-x on Intel or AMD-V on AMD, which drivers such as kvm2 require. Non-x86 hosts return false.
+func HasVirtualizationExtensions() bool {
+	if !isX86() {
+		return false
+	}
+	return hasVirtualizationExtensions()
+}
+
+// cpuidHasVirtualization returns true if CPUID reports VT-x, CPUID.1:ECX[5], or AMD-V, CPUID.80000001H:ECX[2]
+func cpuidHasVirtualization() bool {
+	if _, _, ecx, _ := readCPUID(1, 0); ecx&(1<<5) != 0 {
+		return true
+	}
+	if maxExt, _, _, _ := readCPUID(0x80000000, 0); maxExt < 0x80000001 {
+		return false
+	}
+	_, _, ecx, _ := readCPUID(0x80000001, 0)
+	return ecx&(1<<2) != 0
+}
+
+// cpuinfoHasVirtualization returns true if cpuinfo lists the VT-x ("vmx") or AMD-V ("svm") flag
+func cpuinfoHasVirtualization(cpuinfo string) bool {
+	return cpuinfoHasFlag(cpuinfo, "vmx") || cpuinfoHasFlag(cpuinfo, "svm")
+}
+
+// IsNestedVirtualizationSupported returns true if VMs started by minikube could themselves run hardware accelerated VMs.
+// Inside a VM this means the outer hypervisor passes the CPU virtualization extensions through and /dev/kvm is usable;
+// on bare metal it means the kvm module was loaded with nested virtualization turned on.
+// Only Linux is supported; other platforms return false.
+func IsNestedVirtualizationSupported() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return nestedVirtualizationSupported(DetectHypervisor())
+}
+
+func nestedVirtualizationSupported(hypervisor string) bool {
+	if !cpuinfoHasVirtualization(readCPUInfo()) {
+		return false
+	}
+	if hypervisor != "" {
+		return fileExists("/dev/kvm")
+	}
+	return kvmNestedEnabled()
+}
+
+// kvmNestedEnabled returns true if the kvm_intel or kvm_amd module allows nested guests
+func kvmNestedEnabled() bool {
+	return kvmModuleParamEnabled("kvm_intel", "nested") || kvmModuleParamEnabled("kvm_amd", "nested")
+}
+
+// kvmModuleParamEnabled returns true if the boolean parameter of the given kvm module is enabled
+func kvmModuleParamEnabled(module, param string) bool {
+	b, err := os.ReadFile(hostPath("/sys/module/" + module + "/parameters/" + param))
+	if err != nil {
+		return false
+	}
+	switch strings.TrimSpace(string(b)) {
+	case "Y", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func detectHypervisor() string {
+	present, err := unix.SysctlUint32("kern.hv_vmm_present")
+	if err != nil || present == 0 {
+		return ""
+	}
+	model, err := unix.Sysctl("hw.model")
+	if err != nil {
+		return HypervisorUnknown
+	}
+	// hw.model names the virtual platform, such as "VMware7,1"; Apple Virtualization.framework
+	// guests report "VirtualMac2,1", which matches no known platform and is reported as unknown
+	if h := hypervisorFromDMI("", model); h != "" {
+		return h
+	}
+	return HypervisorUnknown
+}
+
+func hasVirtualizationExtensions() bool {
+	// set when the CPU supports Hypervisor.framework, which requires VT-x on Intel Macs
+	supported, err := unix.SysctlUint32("kern.hv_support")
+	return err == nil && supported == 1
+}
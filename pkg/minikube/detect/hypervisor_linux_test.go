@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+func TestHasVirtualizationExtensionsLinux(t *testing.T) {
+	vmx := "processor\t: 0\nflags\t\t: fpu vme de pse vmx lahf_lm\n"
+	plain := "processor\t: 0\nflags\t\t: fpu vme de pse lahf_lm\n"
+
+	tests := []struct {
+		name    string
+		cpuid   bool
+		cpuinfo string
+		want    bool
+	}{
+		{"cpuid", true, plain, true},
+		{"cpuinfo only", false, vmx, true},
+		{"neither", false, plain, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := readCPUID
+			t.Cleanup(func() { readCPUID = orig })
+			readCPUID = func(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32) {
+				if eaxArg == 1 && tc.cpuid {
+					return 0, 0, 1 << 5, 0
+				}
+				return 0, 0, 0, 0
+			}
+			fakeHostRoot(t, map[string]string{"/proc/cpuinfo": tc.cpuinfo})
+			if got := hasVirtualizationExtensions(); got != tc.want {
+				t.Errorf("hasVirtualizationExtensions() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// fakeCPUID makes readCPUID report the given hypervisor signature in leaf 0x40000000,
+// with the hypervisor present bit set unless sig is empty
+func fakeCPUID(t *testing.T, sig string) {
+	t.Helper()
+	orig := readCPUID
+	t.Cleanup(func() { readCPUID = orig })
+	readCPUID = func(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32) {
+		switch {
+		case eaxArg == 1 && sig != "":
+			return 0, 0, 1 << 31, 0
+		case eaxArg == 0x40000000 && sig != "":
+			b := []byte((sig + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")[:12])
+			return 0x40000001, binary.LittleEndian.Uint32(b[0:]), binary.LittleEndian.Uint32(b[4:]), binary.LittleEndian.Uint32(b[8:])
+		}
+		return 0, 0, 0, 0
+	}
+}
+
+// fakeHyperVPartition makes readCPUID report the Hyper-V partition privileges of leaf 0x40000003 on top of
+// fakeCPUID, granting CreatePartitions to the root partition
+func fakeHyperVPartition(t *testing.T, root bool) {
+	t.Helper()
+	fakeCPUID(t, "Microsoft Hv")
+	inner := readCPUID
+	readCPUID = func(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32) {
+		eax, ebx, ecx, edx = inner(eaxArg, ecxArg)
+		switch eaxArg {
+		case 0x40000000:
+			eax = 0x4000000b
+		case 0x40000003:
+			ebx = 0x2e7e
+			if root {
+				ebx |= 1
+			}
+		}
+		return eax, ebx, ecx, edx
+	}
+}
+
+func TestHypervisorFromCPUID(t *testing.T) {
+	tests := []struct {
+		name string
+		sig  string
+		want string
+	}{
+		{"bare metal", "", ""},
+		{"kvm", "KVMKVMKVM", HypervisorKVM},
+		{"hyper-v", "Microsoft Hv", HypervisorHyperV},
+		{"vmware", "VMwareVMware", HypervisorVMware},
+		{"xen", "XenVMMXenVMM", HypervisorXen},
+		{"qemu tcg", "TCGTCGTCGTCG", HypervisorQEMU},
+		{"parallels", " lrpepyh  vr", HypervisorParallels},
+		{"virtualbox", "VBoxVBoxVBox", HypervisorVirtualBox},
+		{"apple", "Apple VZ", HypervisorUnknown},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeCPUID(t, tc.sig)
+			if got := hypervisorFromCPUID(); got != tc.want {
+				t.Errorf("hypervisorFromCPUID() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHypervisorFromCPUIDHyperVPartition(t *testing.T) {
+	tests := []struct {
+		name string
+		root bool
+		want string
+	}{
+		{"guest partition", false, HypervisorHyperV},
+		{"root partition", true, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHyperVPartition(t, tc.root)
+			if got := hypervisorFromCPUID(); got != tc.want {
+				t.Errorf("hypervisorFromCPUID() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHypervisorFromCPUIDBitClear(t *testing.T) {
+	// leaf 0x40000000 is undefined without the hypervisor present bit and must be ignored
+	orig := readCPUID
+	t.Cleanup(func() { readCPUID = orig })
+	readCPUID = func(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32) {
+		if eaxArg == 0x40000000 {
+			return 0x40000001, 0x4b4d564b, 0x564b4d56, 0x0000004d
+		}
+		return 0, 0, 0, 0
+	}
+	if got := hypervisorFromCPUID(); got != "" {
+		t.Errorf("hypervisorFromCPUID() = %q, want \"\"", got)
+	}
+}
+
+func TestHypervisorFromDMI(t *testing.T) {
+	tests := []struct {
+		vendor  string
+		product string
+		want    string
+	}{
+		{"innotek GmbH", "VirtualBox", HypervisorVirtualBox},
+		{"VMware, Inc.", "VMware Virtual Platform", HypervisorVMware},
+		{"VMware, Inc.", "VMware20,1", HypervisorVMware},
+		{"Microsoft Corporation", "Virtual Machine", HypervisorHyperV},
+		{"Xen", "HVM domU", HypervisorXen},
+		{"Red Hat", "KVM", HypervisorKVM},
+		{"QEMU", "Standard PC (Q35 + ICH9, 2009)", HypervisorQEMU},
+		{"Parallels Software International Inc.", "Parallels Virtual Platform", HypervisorParallels},
+		{"", "VirtualMac2,1", ""},
+		{"Dell Inc.", "PowerEdge R640", ""},
+		{"LENOVO", "20XW0055US", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.product, func(t *testing.T) {
+			if got := hypervisorFromDMI(tc.vendor, tc.product); got != tc.want {
+				t.Errorf("hypervisorFromDMI(%q, %q) = %q, want %q", tc.vendor, tc.product, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHypervisorFromSysfs(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		sig   string
+		want  string
+	}{
+		{"xen pv", map[string]string{"/sys/hypervisor/type": "xen\n"}, "", HypervisorXen},
+		{"qemu", map[string]string{
+			"/sys/class/dmi/id/sys_vendor":   "QEMU\n",
+			"/sys/class/dmi/id/product_name": "Standard PC (i440FX + PIIX, 1996)\n",
+		}, "", HypervisorQEMU},
+		{"gce", map[string]string{
+			"/sys/class/dmi/id/sys_vendor":   "Google\n",
+			"/sys/class/dmi/id/product_name": "Google Compute Engine\n",
+			"/proc/cpuinfo":                  "processor\t: 0\nflags\t\t: fpu vme de pse hypervisor lahf_lm\n",
+		}, "", HypervisorUnknown},
+		{"gce cpuid", map[string]string{
+			"/sys/class/dmi/id/sys_vendor":   "Google\n",
+			"/sys/class/dmi/id/product_name": "Google Compute Engine\n",
+		}, "KVMKVMKVM", HypervisorKVM},
+		{"bare metal", map[string]string{
+			"/sys/class/dmi/id/sys_vendor":   "Dell Inc.\n",
+			"/sys/class/dmi/id/product_name": "PowerEdge R640\n",
+			"/proc/cpuinfo":                  "processor\t: 0\nflags\t\t: fpu vme de pse vmx lahf_lm\n",
+		}, "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			fakeCPUID(t, tc.sig)
+			if got := hypervisorFromSysfs(); got != tc.want {
+				t.Errorf("hypervisorFromSysfs() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNestedVirtualizationSupported(t *testing.T) {
+	intel := "processor\t: 0\nflags\t\t: fpu vme de pse vmx lahf_lm\n"
+	amd := "processor\t: 0\nflags\t\t: fpu vme de pse svm lahf_lm\n"
+	noVirt := "processor\t: 0\nflags\t\t: fpu vme de pse hypervisor lahf_lm\n"
+
+	tests := []struct {
+		name       string
+		hypervisor string
+		files      map[string]string
+		want       bool
+	}{
+		{"bare metal intel nested", "", map[string]string{"/proc/cpuinfo": intel, "/sys/module/kvm_intel/parameters/nested": "Y\n"}, true},
+		{"bare metal amd nested", "", map[string]string{"/proc/cpuinfo": amd, "/sys/module/kvm_amd/parameters/nested": "1\n"}, true},
+		{"bare metal nested off", "", map[string]string{"/proc/cpuinfo": intel, "/sys/module/kvm_intel/parameters/nested": "N\n"}, false},
+		{"guest with kvm", HypervisorKVM, map[string]string{"/proc/cpuinfo": intel, "/dev/kvm": ""}, true},
+		{"guest without kvm device", HypervisorKVM, map[string]string{"/proc/cpuinfo": intel}, false},
+		{"guest without extensions", HypervisorVMware, map[string]string{"/proc/cpuinfo": noVirt, "/dev/kvm": ""}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := nestedVirtualizationSupported(tc.hypervisor); got != tc.want {
+				t.Errorf("nestedVirtualizationSupported(%q) = %t, want %t", tc.hypervisor, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCPUIDHasVirtualization(t *testing.T) {
+	tests := []struct {
+		name         string
+		ecx1, ecxExt uint32
+		maxExt       uint32
+		want         bool
+	}{
+		{"intel vt-x", 1 << 5, 0, 0x80000008, true},
+		{"amd-v", 0, 1 << 2, 0x80000008, true},
+		{"none", 1 << 31, 1 << 0, 0x80000008, false},
+		{"no extended leaves", 0, 1 << 2, 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := readCPUID
+			t.Cleanup(func() { readCPUID = orig })
+			readCPUID = func(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32) {
+				switch eaxArg {
+				case 1:
+					return 0, 0, tc.ecx1, 0
+				case 0x80000000:
+					return tc.maxExt, 0, 0, 0
+				case 0x80000001:
+					return 0, 0, tc.ecxExt, 0
+				}
+				return 0, 0, 0, 0
+			}
+			if got := cpuidHasVirtualization(); got != tc.want {
+				t.Errorf("cpuidHasVirtualization() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCPUInfoHasVirtualization(t *testing.T) {
+	tests := []struct {
+		name    string
+		cpuinfo string
+		want    bool
+	}{
+		{"intel vt-x", "processor\t: 0\nvendor_id\t: GenuineIntel\nflags\t\t: fpu vme de pse tsc msr pae vmx smx est\n", true},
+		{"amd-v", "processor\t: 0\nvendor_id\t: AuthenticAMD\nflags\t\t: fpu vme de pse tsc msr pae svm extapic\n", true},
+		{"disabled in firmware", "processor\t: 0\nvendor_id\t: GenuineIntel\nflags\t\t: fpu vme de pse tsc msr pae smx est\n", false},
+		{"flag substring", "processor\t: 0\nvendor_id\t: AuthenticAMD\nflags\t\t: fpu svm_lock\n", false},
+		{"arm", armCPUInfo, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cpuinfoHasVirtualization(tc.cpuinfo); got != tc.want {
+				t.Errorf("cpuinfoHasVirtualization() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// pfVirtFirmwareEnabled is the IsProcessorFeaturePresent feature for VT-x/AMD-V being enabled by the firmware
+const pfVirtFirmwareEnabled = 21
+
+func detectHypervisor() string {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `HARDWARE\DESCRIPTION\System\BIOS`, registry.QUERY_VALUE)
+	if err == nil {
+		defer k.Close()
+		vendor, _, _ := k.GetStringValue("SystemManufacturer")
+		product, _, _ := k.GetStringValue("SystemProductName")
+		if h := hypervisorFromDMI(vendor, product); h != "" {
+			return h
+		}
+	}
+	return hypervisorFromCPUID()
+}
+
+func hasVirtualizationExtensions() bool {
+	proc := windows.NewLazySystemDLL("kernel32.dll").NewProc("IsProcessorFeaturePresent")
+	if err := proc.Find(); err != nil {
+		return false
+	}
+	present, _, _ := proc.Call(pfVirtFirmwareEnabled)
+	return present != 0
+}
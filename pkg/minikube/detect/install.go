@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// executable returns the path of the running minikube binary. It is a variable so that tests can fake the install location.
+var executable = os.Executable
+
+// resolvedExecutable returns the path of the running minikube binary with symlinks followed,
+// since package managers commonly link their installs into a directory on the PATH
+func resolvedExecutable() (string, error) {
+	ex, err := executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(ex); err == nil {
+		return resolved, nil
+	}
+	return ex, nil
+}
+
+// underDir returns true if path is dir or is inside of it
+func underDir(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	if runtime.GOOS == "windows" {
+		// paths differing only by case refer to the same file
+		path, dir = strings.ToLower(path), strings.ToLower(dir)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// homebrewCellars returns the directories Homebrew installs packages to. The Homebrew prefix itself is not one of them:
+// on Intel Macs it is /usr/local, where minikube is also installed by hand.
+func homebrewCellars() []string {
+	cellars := []string{os.Getenv("HOMEBREW_CELLAR")}
+	if prefix := os.Getenv("HOMEBREW_PREFIX"); prefix != "" {
+		cellars = append(cellars, filepath.Join(prefix, "Cellar"))
+	}
+	return append(cellars,
+		"/opt/homebrew/Cellar",              // macOS on Apple Silicon
+		"/usr/local/Cellar",                 // macOS on Intel
+		"/home/linuxbrew/.linuxbrew/Cellar", // Linux
+	)
+}
+
+// MinikubeInstalledViaHomebrew returns true if the minikube binary was installed by Homebrew, which links it from its
+// Cellar into the bin directory of the Homebrew prefix
+func MinikubeInstalledViaHomebrew() bool {
+	ex, err := resolvedExecutable()
+	if err != nil {
+		return false
+	}
+	for _, cellar := range homebrewCellars() {
+		if underDir(ex, cellar) {
+			return true
+		}
+	}
+	return false
+}
+
+// MinikubeInstalledViaFlatpak returns true if minikube is running from a Flatpak, whose sandbox limits
+// the filesystem and sockets, like the Docker socket, that drivers can access
+func MinikubeInstalledViaFlatpak() bool {
+	if os.Getenv("FLATPAK_ID") != "" {
+		return true
+	}
+	ex, err := resolvedExecutable()
+	if err != nil {
+		return false
+	}
+	// flatpak mounts the application at /app inside of the sandbox
+	return underDir(ex, "/app")
+}
+
+// MinikubeInstalledViaAppImage returns true if minikube is running from an AppImage, which cannot be updated in place
+func MinikubeInstalledViaAppImage() bool {
+	// the AppImage runtime sets APPIMAGE to the path of the .AppImage file
+	return os.Getenv("APPIMAGE") != ""
+}
+
+// MinikubeInstalledViaDEB returns true if the minikube binary is owned by a dpkg package
+func MinikubeInstalledViaDEB() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	ex, err := resolvedExecutable()
+	if err != nil {
+		return false
+	}
+	// owned files are listed as "<package>: <path>", dpkg fails for files that are not
+	o, err := runCommand("dpkg", "-S", ex)
+	if err != nil {
+		return false
+	}
+	pkg, _, found := strings.Cut(strings.TrimSpace(string(o)), ": ")
+	return found && pkg != ""
+}
+
+// MinikubeInstalledViaRPM returns true if the minikube binary is owned by an rpm package
+func MinikubeInstalledViaRPM() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	ex, err := resolvedExecutable()
+	if err != nil {
+		return false
+	}
+	// rpm prints the owning package, or fails with "file ... is not owned by any package"
+	o, err := runCommand("rpm", "-qf", ex)
+	if err != nil {
+		return false
+	}
+	pkg := strings.TrimSpace(string(o))
+	return pkg != "" && !strings.Contains(pkg, "not owned")
+}
+
+// installMethods are the install method detectors consulted by InstallMethod, from the most to the least specific.
+// Sandboxed formats come first since they may bundle a binary that would otherwise look unmanaged.
+var installMethods = []struct {
+	name   string
+	detect func() bool
+}{
+	{"flatpak", MinikubeInstalledViaFlatpak},
+	{"snap", MinikubeInstalledViaSnap},
+	{"appimage", MinikubeInstalledViaAppImage},
+	{"homebrew", MinikubeInstalledViaHomebrew},
+	{"choco", MinikubeInstalledViaChoco},
+	{"scoop", MinikubeInstalledViaScoop},
+	{"winget", MinikubeInstalledViaWinget},
+	{"deb", MinikubeInstalledViaDEB},
+	{"rpm", MinikubeInstalledViaRPM},
+}
+
+// installMethodCacheKey is the detect cache entry of InstallMethod
+const installMethodCacheKey = "install-method"
+
+// InstallMethod returns how the minikube binary was installed: "snap", "homebrew", "flatpak", "appimage", "choco",
+// "scoop", "winget", "deb", "rpm", or "binary" when it is not managed by a package manager.
+// The result is cached until ResetInstallMethodDetection.
+func InstallMethod() string {
+	return cachedString(installMethodCacheKey, 0, func() string {
+		for _, m := range installMethods {
+			if m.detect() {
+				return m.name
+			}
+		}
+		return "binary"
+	})
+}
+
+// ResetInstallMethodDetection discards the cached InstallMethod result, for use in tests.
+func ResetInstallMethodDetection() {
+	forgetDetection(installMethodCacheKey)
+}
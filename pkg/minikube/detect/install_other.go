@@ -0,0 +1,34 @@
+//go:build !windows
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+// MinikubeInstalledViaChoco returns true if the minikube binary was installed by Chocolatey, which is Windows only
+func MinikubeInstalledViaChoco() bool {
+	return false
+}
+
+// MinikubeInstalledViaScoop returns true if the minikube binary was installed by Scoop, which is Windows only
+func MinikubeInstalledViaScoop() bool {
+	return false
+}
+
+// MinikubeInstalledViaWinget returns true if the minikube binary was installed by winget, which is Windows only
+func MinikubeInstalledViaWinget() bool {
+	return false
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeExecutable makes the running minikube binary appear to be at path
+func fakeExecutable(t *testing.T, path string) {
+	t.Helper()
+	orig := executable
+	executable = func() (string, error) { return path, nil }
+	t.Cleanup(func() { executable = orig })
+}
+
+func TestUnderDir(t *testing.T) {
+	tests := []struct {
+		path string
+		dir  string
+		want bool
+	}{
+		{"/opt/homebrew/bin/minikube", "/opt/homebrew", true},
+		{"/opt/homebrew", "/opt/homebrew/", true},
+		{"/opt/homebrew-old/bin/minikube", "/opt/homebrew", false},
+		{"/usr/local/bin/minikube", "/opt/homebrew", false},
+		{"/opt/homebrew/../bin/minikube", "/opt/homebrew", false},
+		{"/opt/homebrew/bin/minikube", "", false},
+	}
+
+	for _, tc := range tests {
+		if got := underDir(tc.path, tc.dir); got != tc.want {
+			t.Errorf("underDir(%q, %q) = %v, want %v", tc.path, tc.dir, got, tc.want)
+		}
+	}
+}
+
+func TestMinikubeInstalledViaHomebrew(t *testing.T) {
+	t.Setenv("HOMEBREW_CELLAR", "")
+	t.Setenv("HOMEBREW_PREFIX", "")
+
+	t.Run("apple silicon cellar", func(t *testing.T) {
+		fakeExecutable(t, "/opt/homebrew/Cellar/minikube/1.32.0/bin/minikube")
+		if !MinikubeInstalledViaHomebrew() {
+			t.Error("MinikubeInstalledViaHomebrew() = false, want true")
+		}
+	})
+
+	t.Run("linked from cellar", func(t *testing.T) {
+		// resolve the temp dir, which is itself behind a symlink on macOS
+		prefix, err := filepath.EvalSymlinks(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HOMEBREW_PREFIX", prefix)
+		bin := filepath.Join(prefix, "Cellar", "minikube", "1.32.0", "bin", "minikube")
+		if err := os.MkdirAll(filepath.Dir(bin), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(bin, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+		// the link lives outside of the prefix, like /usr/local/bin is outside of /usr/local/Cellar
+		link := filepath.Join(t.TempDir(), "minikube")
+		if err := os.Symlink(bin, link); err != nil {
+			// creating symlinks requires a privilege on windows
+			t.Skipf("symlinks are not available: %v", err)
+		}
+		fakeExecutable(t, link)
+		if !MinikubeInstalledViaHomebrew() {
+			t.Error("MinikubeInstalledViaHomebrew() = false, want true for a link into the Cellar")
+		}
+	})
+
+	t.Run("unmanaged", func(t *testing.T) {
+		fakeExecutable(t, "/usr/local/bin/minikube")
+		if MinikubeInstalledViaHomebrew() {
+			t.Error("MinikubeInstalledViaHomebrew() = true, want false")
+		}
+	})
+
+	t.Run("installed by hand into the intel prefix", func(t *testing.T) {
+		// brew shellenv sets HOMEBREW_PREFIX to /usr/local on Intel Macs, where the documented manual install puts minikube
+		t.Setenv("HOMEBREW_PREFIX", "/usr/local")
+		t.Setenv("HOMEBREW_CELLAR", "/usr/local/Cellar")
+		fakeExecutable(t, "/usr/local/bin/minikube")
+		if MinikubeInstalledViaHomebrew() {
+			t.Error("MinikubeInstalledViaHomebrew() = true for /usr/local/bin/minikube, which is not a link into the Cellar")
+		}
+
+		prefix, err := filepath.EvalSymlinks(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HOMEBREW_PREFIX", prefix)
+		t.Setenv("HOMEBREW_CELLAR", filepath.Join(prefix, "Cellar"))
+		bin := filepath.Join(prefix, "bin", "minikube")
+		if err := os.MkdirAll(filepath.Dir(bin), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(bin, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+		fakeExecutable(t, bin)
+		if MinikubeInstalledViaHomebrew() {
+			t.Errorf("MinikubeInstalledViaHomebrew() = true for %s, which is not a link into the Cellar", bin)
+		}
+	})
+}
+
+func TestMinikubeInstalledViaFlatpak(t *testing.T) {
+	fakeExecutable(t, "/usr/local/bin/minikube")
+
+	t.Setenv("FLATPAK_ID", "io.k8s.minikube")
+	if !MinikubeInstalledViaFlatpak() {
+		t.Error("MinikubeInstalledViaFlatpak() = false, want true with FLATPAK_ID set")
+	}
+
+	t.Setenv("FLATPAK_ID", "")
+	if MinikubeInstalledViaFlatpak() {
+		t.Error("MinikubeInstalledViaFlatpak() = true, want false")
+	}
+
+	fakeExecutable(t, "/app/bin/minikube")
+	if !MinikubeInstalledViaFlatpak() {
+		t.Error("MinikubeInstalledViaFlatpak() = false, want true for a binary under /app")
+	}
+}
+
+func TestMinikubeInstalledViaAppImage(t *testing.T) {
+	t.Setenv("APPIMAGE", "/home/jdoe/Applications/minikube-x86_64.AppImage")
+	if !MinikubeInstalledViaAppImage() {
+		t.Error("MinikubeInstalledViaAppImage() = false, want true with APPIMAGE set")
+	}
+
+	t.Setenv("APPIMAGE", "")
+	if MinikubeInstalledViaAppImage() {
+		t.Error("MinikubeInstalledViaAppImage() = true, want false")
+	}
+}
+
+func TestMinikubeInstalledViaPackage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("dpkg and rpm installs are only detected on linux")
+	}
+
+	tests := []struct {
+		name     string
+		commands map[string]string
+		deb      bool
+		rpm      bool
+	}{
+		{"deb", map[string]string{"dpkg -S /usr/bin/minikube": "minikube: /usr/bin/minikube\n"}, true, false},
+		{"rpm", map[string]string{"rpm -qf /usr/bin/minikube": "minikube-1.32.0-0.x86_64\n"}, false, true},
+		{"rpm unowned", map[string]string{"rpm -qf /usr/bin/minikube": "file /usr/bin/minikube is not owned by any package\n"}, false, false},
+		{"no package manager", nil, false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeExecutable(t, "/usr/bin/minikube")
+			fakeCommand(t, tc.commands)
+			if got := MinikubeInstalledViaDEB(); got != tc.deb {
+				t.Errorf("MinikubeInstalledViaDEB() = %v, want %v", got, tc.deb)
+			}
+			if got := MinikubeInstalledViaRPM(); got != tc.rpm {
+				t.Errorf("MinikubeInstalledViaRPM() = %v, want %v", got, tc.rpm)
+			}
+		})
+	}
+}
+
+func TestInstallMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		env      map[string]string
+		commands map[string]string
+		want     string
+	}{
+		{"binary", "/usr/local/bin/minikube", nil, nil, "binary"},
+		{"homebrew", "/opt/homebrew/Cellar/minikube/1.32.0/bin/minikube", nil, nil, "homebrew"},
+		{"snap", "/snap/minikube/x1/bin/minikube", nil, nil, "snap"},
+		{"appimage", "/tmp/.mount_minikuAbCdEf/usr/bin/minikube", map[string]string{"APPIMAGE": "/home/jdoe/minikube.AppImage"}, nil, "appimage"},
+		{"flatpak", "/app/bin/minikube", nil, nil, "flatpak"},
+		{"flatpak over appimage", "/app/bin/minikube", map[string]string{"APPIMAGE": "/home/jdoe/minikube.AppImage"}, nil, "flatpak"},
+		{"appimage over homebrew", "/opt/homebrew/Cellar/minikube/1.32.0/bin/minikube", map[string]string{"APPIMAGE": "/home/jdoe/minikube.AppImage"}, nil, "appimage"},
+		{"flatpak env over homebrew", "/opt/homebrew/Cellar/minikube/1.32.0/bin/minikube", map[string]string{"FLATPAK_ID": "io.k8s.minikube"}, nil, "flatpak"},
+		{"snap over deb", "/snap/minikube/x1/bin/minikube", nil, map[string]string{"dpkg -S /snap/minikube/x1/bin/minikube": "minikube: /snap/minikube/x1/bin/minikube"}, "snap"},
+		{"homebrew over rpm", "/home/linuxbrew/.linuxbrew/Cellar/minikube/1.32.0/bin/minikube", nil, map[string]string{"rpm -qf /home/linuxbrew/.linuxbrew/Cellar/minikube/1.32.0/bin/minikube": "minikube-1.32.0-0.x86_64"}, "homebrew"},
+	}
+
+	defer ResetInstallMethodDetection()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range []string{"APPIMAGE", "FLATPAK_ID", "HOMEBREW_CELLAR", "HOMEBREW_PREFIX"} {
+				t.Setenv(k, tc.env[k])
+			}
+			fakeExecutable(t, tc.path)
+			fakeCommand(t, tc.commands)
+			ResetInstallMethodDetection()
+			if got := InstallMethod(); got != tc.want {
+				t.Errorf("InstallMethod() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("memoized", func(t *testing.T) {
+		fakeCommand(t, nil)
+		fakeExecutable(t, "/opt/homebrew/Cellar/minikube/1.32.0/bin/minikube")
+		ResetInstallMethodDetection()
+		InstallMethod()
+		fakeExecutable(t, "/usr/local/bin/minikube")
+		if got := InstallMethod(); got != "homebrew" {
+			t.Errorf("InstallMethod() = %q, want the cached %q", got, "homebrew")
+		}
+		ResetDetectCache()
+		if got := InstallMethod(); got != "binary" {
+			t.Errorf("InstallMethod() = %q after ResetDetectCache, want %q", got, "binary")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// MinikubeInstalledViaChoco returns true if the minikube binary was installed by Chocolatey
+func MinikubeInstalledViaChoco() bool {
+	root := os.Getenv("ChocolateyInstall")
+	if root == "" {
+		root = filepath.Join(os.Getenv("ProgramData"), "chocolatey")
+	}
+	// covers both the package under lib and the shim chocolatey places in bin
+	return executableUnder(root)
+}
+
+// MinikubeInstalledViaScoop returns true if the minikube binary was installed by Scoop, for the user or globally
+func MinikubeInstalledViaScoop() bool {
+	roots := []string{os.Getenv("SCOOP"), os.Getenv("SCOOP_GLOBAL")}
+	if home := os.Getenv("USERPROFILE"); home != "" {
+		roots = append(roots, filepath.Join(home, "scoop"))
+	}
+	if data := os.Getenv("ProgramData"); data != "" {
+		roots = append(roots, filepath.Join(data, "scoop"))
+	}
+	for _, root := range roots {
+		if root != "" && executableUnder(filepath.Join(root, "apps", "minikube")) {
+			return true
+		}
+	}
+	return false
+}
+
+// MinikubeInstalledViaWinget returns true if the minikube binary was installed by winget, for the user or the machine
+func MinikubeInstalledViaWinget() bool {
+	var roots []string
+	if local := os.Getenv("LOCALAPPDATA"); local != "" {
+		roots = append(roots, filepath.Join(local, "Microsoft", "WinGet", "Packages"))
+	}
+	if programs := os.Getenv("ProgramFiles"); programs != "" {
+		roots = append(roots, filepath.Join(programs, "WinGet", "Packages"))
+	}
+	for _, root := range roots {
+		if executableUnder(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// executableUnder returns true if the resolved minikube binary is inside dir
+func executableUnder(dir string) bool {
+	ex, err := resolvedExecutable()
+	if err != nil {
+		return false
+	}
+	return underDir(ex, dir)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+func TestWindowsPackageManagers(t *testing.T) {
+	t.Setenv("ChocolateyInstall", "")
+	t.Setenv("ProgramData", `C:\ProgramData`)
+	t.Setenv("SCOOP", "")
+	t.Setenv("SCOOP_GLOBAL", "")
+	t.Setenv("USERPROFILE", `C:\Users\jdoe`)
+	t.Setenv("LOCALAPPDATA", `C:\Users\jdoe\AppData\Local`)
+	t.Setenv("ProgramFiles", `C:\Program Files`)
+
+	tests := []struct {
+		name   string
+		path   string
+		choco  bool
+		scoop  bool
+		winget bool
+	}{
+		{"choco shim", `C:\ProgramData\chocolatey\bin\minikube.exe`, true, false, false},
+		{"choco package", `C:\ProgramData\Chocolatey\lib\minikube\tools\minikube.exe`, true, false, false},
+		{"scoop", `C:\Users\jdoe\scoop\apps\minikube\current\minikube.exe`, false, true, false},
+		{"scoop global", `C:\ProgramData\scoop\apps\minikube\1.32.0\minikube.exe`, false, true, false},
+		{"scoop other app", `C:\Users\jdoe\scoop\apps\kubectl\current\minikube.exe`, false, false, false},
+		{"winget user", `C:\Users\jdoe\AppData\Local\Microsoft\WinGet\Packages\Kubernetes.minikube_Microsoft.Winget.Source_8wekyb3d8bbwe\minikube.exe`, false, false, true},
+		{"winget machine", `C:\Program Files\WinGet\Packages\Kubernetes.minikube_Microsoft.Winget.Source_8wekyb3d8bbwe\minikube.exe`, false, false, true},
+		{"unmanaged", `C:\Users\jdoe\Downloads\minikube.exe`, false, false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeExecutable(t, tc.path)
+			if got := MinikubeInstalledViaChoco(); got != tc.choco {
+				t.Errorf("MinikubeInstalledViaChoco() = %v, want %v", got, tc.choco)
+			}
+			if got := MinikubeInstalledViaScoop(); got != tc.scoop {
+				t.Errorf("MinikubeInstalledViaScoop() = %v, want %v", got, tc.scoop)
+			}
+			if got := MinikubeInstalledViaWinget(); got != tc.winget {
+				t.Errorf("MinikubeInstalledViaWinget() = %v, want %v", got, tc.winget)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// KVMAvailable returns true if the current user can run KVM guests, as the kvm2 driver requires
+func KVMAvailable() bool {
+	available, _ := KVMStatus()
+	return available
+}
+
+// KVMStatus returns whether the current user can run KVM guests and, if not, why:
+// /dev/kvm may be missing altogether, or be present but not readable and writable by the current user.
+func KVMStatus() (available bool, reason string) {
+	if runtime.GOOS != "linux" {
+		return false, "KVM is only available on Linux"
+	}
+	return kvmStatus()
+}
+
+func kvmStatus() (bool, string) {
+	err := openKVMDevice()
+	switch {
+	case err == nil:
+		return true, ""
+	case os.IsNotExist(err):
+		return false, "/dev/kvm does not exist: enable virtualization in the BIOS and load the kvm_intel or kvm_amd module"
+	case os.IsPermission(err):
+		return false, "/dev/kvm is not readable and writable by the current user: add the user to the group owning it, usually kvm or libvirt"
+	default:
+		return false, fmt.Sprintf("unable to open /dev/kvm: %v", err)
+	}
+}
+
+// openKVMDevice returns why /dev/kvm cannot be opened for reading and writing, or nil if it can
+func openKVMDevice() error {
+	f, err := os.OpenFile(hostPath("/dev/kvm"), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// kvmWarnings warns when /dev/kvm exists but the current user may not use it
+func kvmWarnings(context.Context) []Warning {
+	if runtime.GOOS != "linux" || !os.IsPermission(openKVMDevice()) {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "kvm",
+		Severity:    SeverityError,
+		Message:     "/dev/kvm is not readable and writable by the current user",
+		Remediation: "add the user to the group owning /dev/kvm, usually kvm or libvirt, then log in again",
+	}}
+}
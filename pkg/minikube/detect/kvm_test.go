@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestKVMStatus(t *testing.T) {
+	t.Run("accessible", func(t *testing.T) {
+		fakeHostRoot(t, map[string]string{"/dev/kvm": ""})
+		if ok, reason := kvmStatus(); !ok {
+			t.Errorf("kvmStatus() = false, %q for an accessible /dev/kvm", reason)
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		fakeHostRoot(t, map[string]string{})
+		ok, reason := kvmStatus()
+		if ok || !strings.Contains(reason, "does not exist") {
+			t.Errorf("kvmStatus() = %t, %q, want false and a missing device reason", ok, reason)
+		}
+	})
+	t.Run("permission denied", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes do not restrict access on windows")
+		}
+		if os.Geteuid() == 0 {
+			t.Skip("root bypasses file permissions")
+		}
+		root := fakeHostRoot(t, map[string]string{"/dev/kvm": ""})
+		if err := os.Chmod(filepath.Join(root, "dev", "kvm"), 0); err != nil {
+			t.Fatalf("failed to restrict the fake /dev/kvm: %v", err)
+		}
+		ok, reason := kvmStatus()
+		if ok || !strings.Contains(reason, "not readable and writable") {
+			t.Errorf("kvmStatus() = %t, %q, want false and a permission reason", ok, reason)
+		}
+	})
+}
+
+func TestKVMStatusUnsupported(t *testing.T) {
+	if runtime.GOOS == "linux" {
+		t.Skip("KVM is supported on Linux")
+	}
+	if KVMAvailable() {
+		t.Error("KVMAvailable() = true off Linux")
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// detectCacheErrorTTL is the longest a failed detection is reused, so that a transient failure
+// (a slow metadata server, a docker daemon still starting) is retried during the same command
+const detectCacheErrorTTL = 10 * time.Second
+
+// detectCacheEntry is the outcome of a detector, shared by every caller asking for it
+type detectCacheEntry struct {
+	// done is closed once value and err are set
+	done    chan struct{}
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+// detectCache holds the outcome of expensive detectors, keyed by detector name
+var detectCache = struct {
+	sync.Mutex
+	entries map[string]*detectCacheEntry
+}{entries: map[string]*detectCacheEntry{}}
+
+// cacheNow is the clock of the detect cache. It is a variable so that tests can expire entries.
+var cacheNow = time.Now
+
+// cached returns the outcome of the detector called name, running compute if no live outcome is cached.
+// A successful outcome is reused for ttl, or until ResetDetectCache if ttl is 0. A failed one is reused
+// for a shorter time, see errorTTL. Concurrent callers share a single run of compute.
+// If compute panics, the callers waiting on it get an error, nothing is cached and the panic is propagated.
+func cached(name string, ttl time.Duration, compute func() (interface{}, error)) (interface{}, error) {
+	detectCache.Lock()
+	e, ok := detectCache.entries[name]
+	if ok && !e.expired() {
+		detectCache.Unlock()
+		<-e.done
+		return e.value, e.err
+	}
+	e = &detectCacheEntry{done: make(chan struct{})}
+	detectCache.entries[name] = e
+	detectCache.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.value, e.err = nil, fmt.Errorf("detector %s panicked: %v", name, r)
+			detectCache.Lock()
+			if detectCache.entries[name] == e {
+				delete(detectCache.entries, name)
+			}
+			detectCache.Unlock()
+			close(e.done)
+			panic(r)
+		}
+		close(e.done)
+	}()
+	e.value, e.err = compute()
+	switch {
+	case e.err != nil:
+		e.expires = cacheNow().Add(errorTTL(ttl))
+	case ttl > 0:
+		e.expires = cacheNow().Add(ttl)
+	}
+	return e.value, e.err
+}
+
+// expired returns true if e holds an outcome that must no longer be reused. It is called with detectCache locked.
+func (e *detectCacheEntry) expired() bool {
+	select {
+	case <-e.done:
+		return !e.expires.IsZero() && !cacheNow().Before(e.expires)
+	default:
+		// still running
+		return false
+	}
+}
+
+// errorTTL returns how long a failure is reused by a detector whose successes are reused for ttl
+func errorTTL(ttl time.Duration) time.Duration {
+	if ttl == 0 || ttl > 2*detectCacheErrorTTL {
+		return detectCacheErrorTTL
+	}
+	return ttl / 2
+}
+
+// cachedBool is cached for detectors reporting a bool
+func cachedBool(name string, ttl time.Duration, probe func() (bool, error)) (bool, error) {
+	v, err := cached(name, ttl, func() (interface{}, error) {
+		return probe()
+	})
+	b, _ := v.(bool)
+	return b, err
+}
+
+// cachedString is cached for detectors reporting a string
+func cachedString(name string, ttl time.Duration, probe func() string) string {
+	v, _ := cached(name, ttl, func() (interface{}, error) {
+		return probe(), nil
+	})
+	str, _ := v.(string)
+	return str
+}
+
+// forgetDetection discards the cached outcome of the named detectors
+func forgetDetection(names ...string) {
+	detectCache.Lock()
+	defer detectCache.Unlock()
+	for _, n := range names {
+		delete(detectCache.entries, n)
+	}
+}
+
+// ResetDetectCache discards every cached detection outcome, for use in tests.
+// Detectors still running complete for their current callers, but are not reused.
+func ResetDetectCache() {
+	detectCache.Lock()
+	defer detectCache.Unlock()
+	detectCache.entries = map[string]*detectCacheEntry{}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCacheClock stubs the clock of the detect cache, returning a function advancing it
+func fakeCacheClock(t *testing.T) func(time.Duration) {
+	t.Helper()
+	now := time.Now()
+	orig := cacheNow
+	cacheNow = func() time.Time { return now }
+	t.Cleanup(func() { cacheNow = orig })
+	ResetDetectCache()
+	t.Cleanup(ResetDetectCache)
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCachedTTL(t *testing.T) {
+	advance := fakeCacheClock(t)
+	calls := 0
+	probe := func() (bool, error) {
+		calls++
+		return true, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if got, err := cachedBool("test", time.Minute, probe); !got || err != nil {
+			t.Fatalf("cachedBool() = %v, %v; want true, nil", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("probe ran %d times within its TTL, want 1", calls)
+	}
+
+	advance(time.Minute)
+	if _, err := cachedBool("test", time.Minute, probe); err != nil {
+		t.Fatalf("cachedBool() returned unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("probe ran %d times after its TTL, want 2", calls)
+	}
+
+	// a TTL of 0 keeps the outcome until the cache is reset
+	advance(24 * time.Hour)
+	_, _ = cachedBool("forever", 0, probe)
+	advance(24 * time.Hour)
+	_, _ = cachedBool("forever", 0, probe)
+	if calls != 3 {
+		t.Errorf("probe without TTL ran %d times, want 3", calls)
+	}
+	ResetDetectCache()
+	_, _ = cachedBool("forever", 0, probe)
+	if calls != 4 {
+		t.Errorf("probe ran %d times after ResetDetectCache, want 4", calls)
+	}
+}
+
+func TestCachedErrorTTL(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{0, detectCacheErrorTTL},
+		{time.Hour, detectCacheErrorTTL},
+		{5 * time.Second, 2500 * time.Millisecond},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ttl.String(), func(t *testing.T) {
+			advance := fakeCacheClock(t)
+			calls := 0
+			probe := func() (bool, error) {
+				calls++
+				return false, fmt.Errorf("probe error")
+			}
+
+			if _, err := cachedBool("test", tc.ttl, probe); err == nil {
+				t.Fatal("cachedBool() did not return the probe error")
+			}
+			advance(tc.want - time.Millisecond)
+			if _, err := cachedBool("test", tc.ttl, probe); err == nil {
+				t.Fatal("cachedBool() did not return the cached probe error")
+			}
+			if calls != 1 {
+				t.Errorf("failed probe ran %d times within its error TTL, want 1", calls)
+			}
+			advance(time.Millisecond)
+			_, _ = cachedBool("test", tc.ttl, probe)
+			if calls != 2 {
+				t.Errorf("failed probe ran %d times after its error TTL, want 2", calls)
+			}
+		})
+	}
+}
+
+func TestCachedConcurrent(t *testing.T) {
+	fakeCacheClock(t)
+	var calls int32
+	release := make(chan struct{})
+	probe := func() (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return true, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, _ := cachedBool("test", 0, probe); !got {
+				t.Error("cachedBool() = false, want true")
+			}
+		}()
+	}
+	// give the callers time to pile up on the running probe
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("probe ran %d times for concurrent callers, want 1", calls)
+	}
+}
+
+func TestCachedPanic(t *testing.T) {
+	fakeCacheClock(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer func() { _ = recover() }()
+		cachedBool("test", 0, func() (bool, error) {
+			close(started)
+			<-release
+			panic("probe failed")
+		})
+	}()
+	<-started
+
+	waited := make(chan error)
+	go func() {
+		_, err := cachedBool("test", 0, func() (bool, error) { return true, nil })
+		waited <- err
+	}()
+	// give the caller time to wait on the running probe
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-waited; err == nil {
+		t.Error("cachedBool() = nil error for a caller waiting on a probe that panicked")
+	}
+
+	if got, err := cachedBool("test", 0, func() (bool, error) { return true, nil }); err != nil || !got {
+		t.Errorf("cachedBool() = %t, %v after a probe panicked, want it to run the probe again", got, err)
+	}
+}
+
+func BenchmarkIsOnGCE(b *testing.B) {
+	var requests int32
+	fakeMetadataServer(b, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set("Metadata-Flavor", "Google")
+		},
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		atomic.StoreInt32(&requests, 0)
+		for i := 0; i < b.N; i++ {
+			_, _ = isOnGCE(context.Background())
+		}
+		b.ReportMetric(float64(atomic.LoadInt32(&requests))/float64(b.N), "requests/op")
+	})
+	b.Run("cached", func(b *testing.B) {
+		ResetCloudDetection()
+		atomic.StoreInt32(&requests, 0)
+		for i := 0; i < b.N; i++ {
+			IsOnGCE()
+		}
+		b.ReportMetric(float64(atomic.LoadInt32(&requests))/float64(b.N), "requests/op")
+	})
+}
+
+func BenchmarkDockerDaemonRunning(b *testing.B) {
+	fakeCommand(b, map[string]string{"docker version --format {{.Server.Version}}": "20.10.21\n"})
+	stubbed := runCommandContext
+	var runs int32
+	runCommandContext = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		atomic.AddInt32(&runs, 1)
+		return stubbed(ctx, name, arg...)
+	}
+	b.Cleanup(func() { forgetDetection(dockerDaemonCacheKey) })
+
+	b.Run("uncached", func(b *testing.B) {
+		atomic.StoreInt32(&runs, 0)
+		for i := 0; i < b.N; i++ {
+			_, _ = dockerServerVersion(context.Background())
+		}
+		b.ReportMetric(float64(atomic.LoadInt32(&runs))/float64(b.N), "execs/op")
+	})
+	b.Run("cached", func(b *testing.B) {
+		forgetDetection(dockerDaemonCacheKey)
+		atomic.StoreInt32(&runs, 0)
+		for i := 0; i < b.N; i++ {
+			DockerDaemonRunning()
+		}
+		b.ReportMetric(float64(atomic.LoadInt32(&runs))/float64(b.N), "execs/op")
+	})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// TotalMemoryMiB returns the physical memory of the host in MiB. In a container, the memory limit of its cgroup
+// is returned instead when it is lower.
+func TotalMemoryMiB() (int, error) {
+	total, err := totalMemoryMiB()
+	if err != nil {
+		return 0, err
+	}
+	if IsInContainer() {
+		total = capMemoryMiB(total, cgroupMemoryLimitMiB())
+	}
+	return total, nil
+}
+
+// AvailableMemoryMiB returns the memory in MiB that can be allocated without swapping. In a container,
+// it is capped by what remains of the memory limit of its cgroup.
+func AvailableMemoryMiB() (int, error) {
+	available, err := availableMemoryMiB()
+	if err != nil {
+		return 0, err
+	}
+	if IsInContainer() {
+		if limit := cgroupMemoryLimitMiB(); limit > 0 {
+			remaining := limit - cgroupMemoryUsageMiB()
+			if remaining < available {
+				available = remaining
+			}
+			if available < 0 {
+				available = 0
+			}
+		}
+	}
+	return available, nil
+}
+
+// memoryWarnings warns when less memory is available than Kubernetes needs
+func memoryWarnings(context.Context) []Warning {
+	available, err := AvailableMemoryMiB()
+	if err != nil || available >= kubeadmMinMemoryMiB {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "memory",
+		Severity:    SeverityWarning,
+		Message:     fmt.Sprintf("only %dMiB of memory is available, less than the %dMiB Kubernetes needs", available, kubeadmMinMemoryMiB),
+		Remediation: "close other applications or run minikube on a host with more memory",
+	}}
+}
+
+// SwapEnabled returns true if any swap device or file is active on the host, which the kubelet refuses by default.
+// It always returns false on non-Linux hosts.
+func SwapEnabled() (bool, error) {
+	if runtime.GOOS != "linux" {
+		return false, nil
+	}
+	return swapEnabled()
+}
+
+func swapEnabled() (bool, error) {
+	b, err := os.ReadFile(hostPath("/proc/swaps"))
+	if err != nil {
+		return false, fmt.Errorf("reading /proc/swaps: %w", err)
+	}
+	// the first line is the "Filename Type Size Used Priority" header
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	return len(lines) > 1, nil
+}
+
+// swapWarnings warns when swap is enabled on the host
+func swapWarnings(context.Context) []Warning {
+	if swap, err := SwapEnabled(); err != nil || !swap {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "swap",
+		Severity:    SeverityInfo,
+		Message:     "swap is enabled on the host, which the kubelet does not support by default",
+		Remediation: "run 'sudo swapoff -a', or let minikube configure the kubelet to tolerate swap",
+	}}
+}
+
+// TransparentHugepagesMode returns the active transparent hugepage setting of the host: "always", "madvise" or "never".
+// It returns "" on non-Linux hosts or when the kernel does not support transparent hugepages.
+func TransparentHugepagesMode() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	return transparentHugepagesMode()
+}
+
+func transparentHugepagesMode() string {
+	b, err := os.ReadFile(hostPath("/sys/kernel/mm/transparent_hugepage/enabled"))
+	if err != nil {
+		return ""
+	}
+	return parseBracketedChoice(string(b))
+}
+
+// parseBracketedChoice returns the selected value of a sysfs choice list, e.g. "madvise" for "always [madvise] never"
+func parseBracketedChoice(s string) string {
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(f, "[") && strings.HasSuffix(f, "]") {
+			return strings.Trim(f, "[]")
+		}
+	}
+	return ""
+}
+
+// capMemoryMiB caps n to limit, where a limit of 0 or less means unlimited
+func capMemoryMiB(n, limit int) int {
+	if limit > 0 && limit < n {
+		return limit
+	}
+	return n
+}
+
+// meminfoMiB returns the value of the given /proc/meminfo field in MiB, or 0 if it cannot be read
+func meminfoMiB(key string) int {
+	b, err := os.ReadFile(hostPath("/proc/meminfo"))
+	if err != nil {
+		return 0
+	}
+	// the kernel reports every size field in kB, e.g. "MemTotal:       16318412 kB"
+	kb, err := strconv.Atoi(strings.TrimSuffix(cpuinfoField(string(b), key), " kB"))
+	if err != nil {
+		return 0
+	}
+	return kb / 1024
+}
+
+// meminfoMemoryMiB returns the MemTotal, or MemAvailable, field of /proc/meminfo in MiB
+func meminfoMemoryMiB(key string) (int, error) {
+	n := meminfoMiB(key)
+	if n == 0 {
+		return 0, fmt.Errorf("unable to read %s from /proc/meminfo", key)
+	}
+	return n, nil
+}
+
+// parseVMStat returns the memory in MiB that macOS can allocate without swapping from the output of vm_stat:
+// the free pages, plus the inactive and speculative pages it reclaims on demand
+func parseVMStat(out string) (int, error) {
+	pageSize := int64(4096)
+	if _, rest, found := strings.Cut(out, "page size of "); found {
+		if n, err := strconv.ParseInt(strings.Fields(rest)[0], 10, 64); err == nil {
+			pageSize = n
+		}
+	}
+	var pages int64
+	found := false
+	for _, key := range []string{"Pages free", "Pages inactive", "Pages speculative"} {
+		v := strings.TrimSuffix(cpuinfoField(out, key), ".")
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected vm_stat %s: %q", key, v)
+		}
+		pages += n
+		found = true
+	}
+	if !found {
+		return 0, fmt.Errorf("unexpected vm_stat output: %q", out)
+	}
+	return int(pages * pageSize >> 20), nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func totalMemoryMiB() (int, error) {
+	b, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, fmt.Errorf("sysctl hw.memsize: %w", err)
+	}
+	return int(b >> 20), nil
+}
+
+func availableMemoryMiB() (int, error) {
+	o, err := runCommand("vm_stat")
+	if err != nil {
+		return 0, fmt.Errorf("vm_stat: %w", err)
+	}
+	return parseVMStat(string(o))
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+const meminfoFixture = `MemTotal:        8029032 kB
+MemFree:          562080 kB
+MemAvailable:    4194304 kB
+Buffers:          102400 kB
+`
+
+func TestMemoryMiB(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         map[string]string
+		wantTotal     int
+		wantAvailable int
+	}{
+		{"host", map[string]string{"/proc/meminfo": meminfoFixture}, 7840, 4096},
+		{"container without limit", map[string]string{
+			"/proc/meminfo":             meminfoFixture,
+			"/.dockerenv":               "",
+			"/sys/fs/cgroup/memory.max": "max\n",
+		}, 7840, 4096},
+		{"container with v2 limit", map[string]string{
+			"/proc/meminfo":                 meminfoFixture,
+			"/.dockerenv":                   "",
+			"/sys/fs/cgroup/memory.max":     "2147483648\n",
+			"/sys/fs/cgroup/memory.current": "536870912\n",
+		}, 2048, 1536},
+		{"container with v1 limit", map[string]string{
+			"/proc/meminfo": meminfoFixture,
+			"/.dockerenv":   "",
+			"/sys/fs/cgroup/memory/memory.limit_in_bytes": "1073741824\n",
+			"/sys/fs/cgroup/memory/memory.usage_in_bytes": "1610612736\n",
+		}, 1024, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("container", "")
+			fakeHostRoot(t, tc.files)
+			total, err := TotalMemoryMiB()
+			if err != nil {
+				t.Fatalf("TotalMemoryMiB() error = %v", err)
+			}
+			if total != tc.wantTotal {
+				t.Errorf("TotalMemoryMiB() = %d, want %d", total, tc.wantTotal)
+			}
+			available, err := AvailableMemoryMiB()
+			if err != nil {
+				t.Fatalf("AvailableMemoryMiB() error = %v", err)
+			}
+			if available != tc.wantAvailable {
+				t.Errorf("AvailableMemoryMiB() = %d, want %d", available, tc.wantAvailable)
+			}
+		})
+	}
+}
+
+func TestMemoryMiBWithoutMeminfo(t *testing.T) {
+	fakeHostRoot(t, map[string]string{})
+	if _, err := TotalMemoryMiB(); err == nil {
+		t.Error("TotalMemoryMiB() succeeded without /proc/meminfo")
+	}
+	if _, err := AvailableMemoryMiB(); err == nil {
+		t.Error("AvailableMemoryMiB() succeeded without /proc/meminfo")
+	}
+}
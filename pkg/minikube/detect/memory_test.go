@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+const vmStatOutput = `Mach Virtual Memory Statistics: (page size of 16384 bytes)
+Pages free:                               65536.
+Pages active:                            400000.
+Pages inactive:                          131072.
+Pages speculative:                        65536.
+Pages throttled:                              0.
+Pages wired down:                        150000.
+`
+
+func TestParseVMStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    int
+		wantErr bool
+	}{
+		// (65536 + 131072 + 65536) pages of 16 KiB
+		{"apple silicon", vmStatOutput, 4096, false},
+		{"intel", "Mach Virtual Memory Statistics: (page size of 4096 bytes)\nPages free:  262144.\n", 1024, false},
+		{"garbage", "vm_stat: command not found\n", 0, true},
+		{"malformed count", "Mach Virtual Memory Statistics: (page size of 4096 bytes)\nPages free:  lots.\n", 0, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseVMStat(tc.out)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseVMStat() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("parseVMStat() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCapMemoryMiB(t *testing.T) {
+	tests := []struct {
+		n, limit, want int
+	}{
+		{8192, 2048, 2048},
+		{2048, 8192, 2048},
+		{8192, 0, 8192},
+	}
+
+	for _, tc := range tests {
+		if got := capMemoryMiB(tc.n, tc.limit); got != tc.want {
+			t.Errorf("capMemoryMiB(%d, %d) = %d, want %d", tc.n, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestSwapEnabled(t *testing.T) {
+	header := "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    bool
+		wantErr bool
+	}{
+		{"no swap", map[string]string{"/proc/swaps": header}, false, false},
+		{"swap partition", map[string]string{"/proc/swaps": header + "/dev/sda2                               partition\t8388604\t\t0\t\t-2\n"}, true, false},
+		{"swap file and zram", map[string]string{"/proc/swaps": header + "/swapfile file 2097148 0 -2\n/dev/zram0 partition 4194300 1024 100\n"}, true, false},
+		{"missing", map[string]string{}, false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			got, err := swapEnabled()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("swapEnabled() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("swapEnabled() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTransparentHugepagesMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"always", map[string]string{"/sys/kernel/mm/transparent_hugepage/enabled": "[always] madvise never\n"}, "always"},
+		{"madvise", map[string]string{"/sys/kernel/mm/transparent_hugepage/enabled": "always [madvise] never\n"}, "madvise"},
+		{"never", map[string]string{"/sys/kernel/mm/transparent_hugepage/enabled": "always madvise [never]\n"}, "never"},
+		{"no selection", map[string]string{"/sys/kernel/mm/transparent_hugepage/enabled": "always madvise never\n"}, ""},
+		{"unsupported", map[string]string{}, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := transparentHugepagesMode(); got != tc.want {
+				t.Errorf("transparentHugepagesMode() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
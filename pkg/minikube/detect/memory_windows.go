@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// memoryStatusEx is MEMORYSTATUSEX from sysinfoapi.h
+type memoryStatusEx struct {
+	length               uint32
+	memoryLoad           uint32
+	totalPhys            uint64
+	availPhys            uint64
+	totalPageFile        uint64
+	availPageFile        uint64
+	totalVirtual         uint64
+	availVirtual         uint64
+	availExtendedVirtual uint64
+}
+
+func globalMemoryStatus() (memoryStatusEx, error) {
+	status := memoryStatusEx{}
+	status.length = uint32(unsafe.Sizeof(status))
+	proc := windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+	if r, _, err := proc.Call(uintptr(unsafe.Pointer(&status))); r == 0 {
+		return status, fmt.Errorf("GlobalMemoryStatusEx: %w", err)
+	}
+	return status, nil
+}
+
+func totalMemoryMiB() (int, error) {
+	status, err := globalMemoryStatus()
+	if err != nil {
+		return 0, err
+	}
+	return int(status.totalPhys >> 20), nil
+}
+
+func availableMemoryMiB() (int, error) {
+	status, err := globalMemoryStatus()
+	if err != nil {
+		return 0, err
+	}
+	return int(status.availPhys >> 20), nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+// interfaceAddrs returns the addresses of the host's network interfaces. It is a variable so that tests can stub it.
+var interfaceAddrs = net.InterfaceAddrs
+
+// FreePort returns a TCP port on the loopback interface that is free at the time of the call,
+// as picked by the operating system from its ephemeral range
+func FreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("listening on an ephemeral port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// FreePortInRange returns the first TCP port between lo and hi, inclusive, that is free on the loopback interface.
+// It is meant for hosts whose firewall only allows a known range of ports.
+func FreePortInRange(lo, hi int) (int, error) {
+	if lo < 1 || hi > 65535 || lo > hi {
+		return 0, fmt.Errorf("invalid port range %d-%d", lo, hi)
+	}
+	for p := lo; p <= hi; p++ {
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(p)))
+		if err != nil {
+			continue
+		}
+		l.Close()
+		return p, nil
+	}
+	return 0, fmt.Errorf("no free port in range %d-%d", lo, hi)
+}
+
+// HasIPv6 returns true if the host has a usable IPv6 stack: the loopback accepts IPv6 connections,
+// and at least one interface has a routable IPv6 address. Link-local addresses only do not count.
+func HasIPv6() bool {
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		klog.Infof("IPv6 loopback is unavailable: %v", err)
+		return false
+	}
+	l.Close()
+
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		klog.Infof("unable to list interface addresses: %v", err)
+		return false
+	}
+	return hasRoutableIPv6(addrs)
+}
+
+// hasRoutableIPv6 returns true if any of addrs is an IPv6 address that is neither loopback nor link-local
+func hasRoutableIPv6(addrs []net.Addr) bool {
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.To4() != nil {
+			continue
+		}
+		// includes unique local addresses (fc00::/7), which are enough for a dual-stack cluster on the host
+		if ipnet.IP.IsGlobalUnicast() {
+			return true
+		}
+	}
+	return false
+}
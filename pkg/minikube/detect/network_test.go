@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestFreePort(t *testing.T) {
+	p, err := FreePort()
+	if err != nil {
+		t.Fatalf("FreePort() error = %v", err)
+	}
+	if p <= 0 || p > 65535 {
+		t.Fatalf("FreePort() = %d, want a valid port", p)
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(p)))
+	if err != nil {
+		t.Fatalf("port %d returned by FreePort() is not free: %v", p, err)
+	}
+	l.Close()
+}
+
+func TestFreePortInRange(t *testing.T) {
+	// hold a port so that the range made only of it is exhausted
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	if p, err := FreePortInRange(busy, busy); err == nil {
+		t.Errorf("FreePortInRange(%d, %d) = %d, want an error for an exhausted range", busy, busy, p)
+	}
+
+	free, err := FreePort()
+	if err != nil {
+		t.Fatalf("FreePort() error = %v", err)
+	}
+	got, err := FreePortInRange(free, free)
+	if err != nil {
+		t.Fatalf("FreePortInRange(%d, %d) error = %v", free, free, err)
+	}
+	if got != free {
+		t.Errorf("FreePortInRange(%d, %d) = %d, want %d", free, free, got, free)
+	}
+
+	for _, r := range [][2]int{{0, 10}, {100, 50}, {65000, 70000}} {
+		if _, err := FreePortInRange(r[0], r[1]); err == nil {
+			t.Errorf("FreePortInRange(%d, %d) succeeded, want an invalid range error", r[0], r[1])
+		}
+	}
+}
+
+func TestHasRoutableIPv6(t *testing.T) {
+	cidr := func(s string) net.Addr {
+		ip, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("invalid fixture %q: %v", s, err)
+		}
+		ipnet.IP = ip
+		return ipnet
+	}
+	tests := []struct {
+		name  string
+		addrs []net.Addr
+		want  bool
+	}{
+		{"ipv4 only", []net.Addr{cidr("127.0.0.1/8"), cidr("192.168.1.10/24")}, false},
+		{"loopback only", []net.Addr{cidr("127.0.0.1/8"), cidr("::1/128")}, false},
+		{"link-local only", []net.Addr{cidr("::1/128"), cidr("fe80::1c2d:3eff:fe4f:5a6b/64")}, false},
+		{"global", []net.Addr{cidr("fe80::1/64"), cidr("2001:db8::10/64")}, true},
+		{"unique local", []net.Addr{cidr("fd00:1234::2/64")}, true},
+		{"none", nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hasRoutableIPv6(tc.addrs); got != tc.want {
+				t.Errorf("hasRoutableIPv6() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasIPv6(t *testing.T) {
+	// the result depends on the runner, which may or may not have IPv6
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		if HasIPv6() {
+			t.Errorf("HasIPv6() = true, but the IPv6 loopback is unavailable: %v", err)
+		}
+		return
+	}
+	l.Close()
+
+	orig := interfaceAddrs
+	t.Cleanup(func() { interfaceAddrs = orig })
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}}, nil
+	}
+	if HasIPv6() {
+		t.Error("HasIPv6() = true with only a link-local address")
+	}
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)}}, nil
+	}
+	if !HasIPv6() {
+		t.Error("HasIPv6() = false with a global address")
+	}
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedOS is returned by detectors that do not apply to the host operating system
+var ErrUnsupportedOS = errors.New("unsupported operating system")
+
+// LinuxDistro returns the ID and VERSION_ID of the Linux distribution from os-release, such as "ubuntu" and "22.04".
+// Either is empty when it is not set, as with the VERSION_ID of rolling releases, and both are empty on other operating systems.
+func LinuxDistro() (id string, versionID string) {
+	if runtime.GOOS != "linux" {
+		return "", ""
+	}
+	return linuxDistro()
+}
+
+func linuxDistro() (string, string) {
+	for _, p := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		b, err := os.ReadFile(hostPath(p))
+		if err != nil {
+			continue
+		}
+		release := parseOSRelease(string(b))
+		return release["ID"], release["VERSION_ID"]
+	}
+	return "", ""
+}
+
+// parseOSRelease parses the shell compatible variable assignments of an os-release file
+func parseOSRelease(content string) map[string]string {
+	vars := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		vars[strings.TrimSpace(k)] = unquoteOSReleaseValue(strings.TrimSpace(v))
+	}
+	return vars
+}
+
+// unquoteOSReleaseValue removes the quotes of an os-release value, which may be double quoted with escapes or single quoted
+func unquoteOSReleaseValue(v string) string {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		if s, err := strconv.Unquote(v); err == nil {
+			return s
+		}
+		return v[1 : len(v)-1]
+	}
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// macOSVersionRe matches product versions such as "14", "14.5" or "10.15.7"
+var macOSVersionRe = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// MacOSVersion returns the product version of macOS, such as "14.5", or an error wrapping ErrUnsupportedOS on other systems
+func MacOSVersion() (string, error) {
+	if runtime.GOOS != "darwin" {
+		return "", fmt.Errorf("%w: %s is not macOS", ErrUnsupportedOS, runtime.GOOS)
+	}
+	o, err := runCommand("sw_vers")
+	if err != nil {
+		return "", fmt.Errorf("sw_vers: %w", err)
+	}
+	return parseSwVers(string(o))
+}
+
+// parseSwVers returns the product version from the output of sw_vers, or of sw_vers -productVersion
+func parseSwVers(out string) (string, error) {
+	v := cpuinfoField(out, "ProductVersion")
+	if v == "" {
+		v = strings.TrimSpace(out)
+	}
+	if !macOSVersionRe.MatchString(v) {
+		return "", fmt.Errorf("unexpected sw_vers output: %q", out)
+	}
+	return v, nil
+}
+
+// KernelVersion returns the release of the running Linux kernel, such as "5.15.0-1234-azure",
+// or an error wrapping ErrUnsupportedOS on other systems
+func KernelVersion() (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("%w: %s is not Linux", ErrUnsupportedOS, runtime.GOOS)
+	}
+	return kernelVersion()
+}
+
+func kernelVersion() (string, error) {
+	if b, err := os.ReadFile(hostPath("/proc/sys/kernel/osrelease")); err == nil {
+		if v := strings.TrimSpace(string(b)); v != "" {
+			return v, nil
+		}
+	}
+	o, err := runCommand("uname", "-r")
+	if err != nil {
+		return "", fmt.Errorf("uname: %w", err)
+	}
+	return strings.TrimSpace(string(o)), nil
+}
+
+// kernelVersionRe matches the numeric prefix of a kernel release
+var kernelVersionRe = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseKernelVersion returns the major, minor and patch numbers of a kernel release such as "5.15.0-1234-azure".
+// Distribution suffixes are ignored and a missing patch number is 0.
+func parseKernelVersion(release string) ([3]int, error) {
+	var v [3]int
+	m := kernelVersionRe.FindStringSubmatch(strings.TrimSpace(release))
+	if m == nil {
+		return v, fmt.Errorf("unable to parse kernel version %q", release)
+	}
+	for i, part := range m[1:] {
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, fmt.Errorf("unable to parse kernel version %q: %w", release, err)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// KernelVersionAtLeast returns true if the kernel release, as returned by KernelVersion, is at least min, such as "5.8".
+// Only the major, minor and patch numbers are compared, so "5.15.0-1234-azure" is at least "5.15".
+func KernelVersionAtLeast(release, min string) (bool, error) {
+	v, err := parseKernelVersion(release)
+	if err != nil {
+		return false, err
+	}
+	m, err := parseKernelVersion(min)
+	if err != nil {
+		return false, err
+	}
+	for i := range v {
+		if v[i] != m[i] {
+			return v[i] > m[i], nil
+		}
+	}
+	return true, nil
+}
+
+// IsMuslLibc returns true if the C library of the Linux host is musl, as on Alpine, rather than glibc.
+// It returns false on other operating systems.
+func IsMuslLibc() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return isMuslLibc()
+}
+
+func isMuslLibc() bool {
+	// the dynamic loader of musl is named like /lib/ld-musl-x86_64.so.1
+	if matches, _ := filepath.Glob(hostPath("/lib/ld-musl-*.so*")); len(matches) > 0 {
+		return true
+	}
+	o, err := runCommand("ldd", "--version")
+	// musl's ldd prints its version to stderr and exits with 1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		o = append(o, exitErr.Stderr...)
+	}
+	return strings.Contains(strings.ToLower(string(o)), "musl")
+}
+
+// InitSystem returns the init system of the Linux host: "systemd", "openrc", "upstart", or "unknown",
+// as for containers whose PID 1 is a shell or a minimal init. It returns "" on other operating systems.
+func InitSystem() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	return initSystem()
+}
+
+func initSystem() string {
+	comm := ""
+	if b, err := os.ReadFile(hostPath("/proc/1/comm")); err == nil {
+		comm = strings.TrimSpace(string(b))
+	}
+	// /run/systemd/system is how sd_booted(3) detects systemd
+	if comm == "systemd" || fileExists("/run/systemd/system") {
+		return "systemd"
+	}
+	switch comm {
+	case "openrc-init":
+		return "openrc"
+	case "init":
+		// openrc and upstart both run from a binary named init
+		if fileExists("/run/openrc") || fileExists("/sbin/rc-service") || fileExists("/usr/sbin/rc-service") {
+			return "openrc"
+		}
+		if o, err := runCommand("initctl", "--version"); err == nil && strings.Contains(string(o), "upstart") {
+			return "upstart"
+		}
+	}
+	return "unknown"
+}
+
+// SELinuxMode returns the SELinux mode of the Linux host: "enforcing", "permissive" or "disabled".
+// It returns "" on other operating systems.
+func SELinuxMode() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	return selinuxMode()
+}
+
+// selinuxWarnings warns when SELinux is enforcing
+func selinuxWarnings(context.Context) []Warning {
+	if SELinuxMode() != "enforcing" {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "selinux",
+		Severity:    SeverityWarning,
+		Message:     "SELinux is enforcing, which can deny the none driver and mounted volumes access to host files",
+		Remediation: "label the shared directories for containers, or run 'sudo setenforce 0' to switch to permissive mode",
+	}}
+}
+
+func selinuxMode() string {
+	// selinuxfs is only mounted when SELinux is enabled
+	if b, err := os.ReadFile(hostPath("/sys/fs/selinux/enforce")); err == nil {
+		switch strings.TrimSpace(string(b)) {
+		case "1":
+			return "enforcing"
+		case "0":
+			return "permissive"
+		}
+	}
+	if o, err := runCommand("getenforce"); err == nil {
+		switch mode := strings.ToLower(strings.TrimSpace(string(o))); mode {
+		case "enforcing", "permissive", "disabled":
+			return mode
+		}
+	}
+	return "disabled"
+}
+
+// AppArmorEnabled returns true if AppArmor is enabled on the Linux host. It returns false on other operating systems.
+func AppArmorEnabled() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return appArmorEnabled()
+}
+
+func appArmorEnabled() bool {
+	b, err := os.ReadFile(hostPath("/sys/module/apparmor/parameters/enabled"))
+	return err == nil && strings.TrimSpace(string(b)) == "Y"
+}
+
+// hostname returns the host name. It is a variable so that tests can fake it.
+var hostname = os.Hostname
+
+// IsChromeOSCrostini returns true if running in the Crostini Linux VM of ChromeOS,
+// which has no nested virtualization, so only the docker driver works
+func IsChromeOSCrostini() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return isChromeOSCrostini()
+}
+
+func isChromeOSCrostini() bool {
+	if fileExists("/dev/.cros_milestone") {
+		return true
+	}
+	// the default container is named penguin, and its apps display through the sommelier Wayland proxy
+	if h, err := hostname(); err != nil || h != "penguin" {
+		return false
+	}
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "SOMMELIER_") {
+			return true
+		}
+	}
+	return false
+}
+
+// RaspberryPiModel returns the model of the Raspberry Pi minikube runs on, such as "Raspberry Pi 4 Model B Rev 1.4",
+// or "" when not running on a Raspberry Pi
+func RaspberryPiModel() string {
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "arm" && runtime.GOARCH != "arm64") {
+		return ""
+	}
+	return raspberryPiModel()
+}
+
+func raspberryPiModel() string {
+	b, err := os.ReadFile(hostPath("/proc/device-tree/model"))
+	if err != nil {
+		return ""
+	}
+	// device tree strings are NUL terminated
+	model := strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+	if !strings.HasPrefix(model, "Raspberry Pi") {
+		return ""
+	}
+	return model
+}
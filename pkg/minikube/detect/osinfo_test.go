@@ -0,0 +1,362 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestLinuxDistro(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		osRelease string
+		id        string
+		versionID string
+	}{
+		{
+			name: "ubuntu",
+			path: "/etc/os-release",
+			osRelease: `PRETTY_NAME="Ubuntu 22.04.3 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+VERSION="22.04.3 LTS (Jammy Jellyfish)"
+VERSION_CODENAME=jammy
+ID=ubuntu
+ID_LIKE=debian
+HOME_URL="https://www.ubuntu.com/"
+`,
+			id:        "ubuntu",
+			versionID: "22.04",
+		},
+		{
+			name: "fedora",
+			path: "/usr/lib/os-release",
+			osRelease: `NAME="Fedora Linux"
+VERSION="39 (Workstation Edition)"
+ID=fedora
+VERSION_ID=39
+PLATFORM_ID="platform:f39"
+PRETTY_NAME="Fedora Linux 39 (Workstation Edition)"
+# a comment
+CPE_NAME="cpe:/o:fedoraproject:fedora:39"
+`,
+			id:        "fedora",
+			versionID: "39",
+		},
+		{
+			name: "arch",
+			path: "/etc/os-release",
+			osRelease: `NAME="Arch Linux"
+PRETTY_NAME="Arch Linux"
+ID=arch
+BUILD_ID=rolling
+ANSI_COLOR="38;2;23;147;209"
+`,
+			id:        "arch",
+			versionID: "",
+		},
+		{
+			name: "alpine",
+			path: "/etc/os-release",
+			osRelease: `NAME="Alpine Linux"
+ID='alpine'
+VERSION_ID=3.18.4
+PRETTY_NAME="Alpine Linux v3.18"
+`,
+			id:        "alpine",
+			versionID: "3.18.4",
+		},
+		{
+			name:      "missing fields",
+			path:      "/etc/os-release",
+			osRelease: "NAME=\"Custom \\\"Linux\\\"\"\n",
+		},
+		{
+			name: "missing",
+			path: "/etc/lsb-release",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, map[string]string{tc.path: tc.osRelease})
+			id, versionID := linuxDistro()
+			if id != tc.id || versionID != tc.versionID {
+				t.Errorf("linuxDistro() = %q, %q, want %q, %q", id, versionID, tc.id, tc.versionID)
+			}
+		})
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	release := parseOSRelease("NAME=\"Custom \\\"Linux\\\"\"\nID = custom\nEMPTY=\n")
+	for k, want := range map[string]string{"NAME": `Custom "Linux"`, "ID": "custom", "EMPTY": ""} {
+		if got := release[k]; got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestParseSwVers(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    string
+		wantErr bool
+	}{
+		{"sonoma", "ProductName:\t\tmacOS\nProductVersion:\t\t14.5\nBuildVersion:\t\t23F79\n", "14.5", false},
+		{"catalina", "ProductName:\tMac OS X\nProductVersion:\t10.15.7\nBuildVersion:\t19H2026\n", "10.15.7", false},
+		{"major only", "ProductName:\t\tmacOS\nProductVersion:\t\t15\nBuildVersion:\t\t24A335\n", "15", false},
+		{"product version flag", "13.6.1\n", "13.6.1", false},
+		{"garbage", "sw_vers: command not found\n", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSwVers(tc.out)
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("parseSwVers() = %q, %v, want %q (error: %v)", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMacOSVersionUnsupported(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS is supported")
+	}
+	if _, err := MacOSVersion(); !errors.Is(err, ErrUnsupportedOS) {
+		t.Errorf("MacOSVersion() error = %v, want ErrUnsupportedOS", err)
+	}
+}
+
+func TestWindowsBuildNumberUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows is supported")
+	}
+	if _, err := WindowsBuildNumber(); !errors.Is(err, ErrUnsupportedOS) {
+		t.Errorf("WindowsBuildNumber() error = %v, want ErrUnsupportedOS", err)
+	}
+}
+
+func TestKernelVersion(t *testing.T) {
+	fakeHostRoot(t, map[string]string{"/proc/sys/kernel/osrelease": "5.15.0-1234-azure\n"})
+	if v, err := kernelVersion(); err != nil || v != "5.15.0-1234-azure" {
+		t.Errorf("kernelVersion() = %q, %v, want %q", v, err, "5.15.0-1234-azure")
+	}
+
+	fakeHostRoot(t, nil)
+	fakeCommand(t, map[string]string{"uname -r": "6.1.0-13-amd64\n"})
+	if v, err := kernelVersion(); err != nil || v != "6.1.0-13-amd64" {
+		t.Errorf("kernelVersion() = %q, %v, want the uname release", v, err)
+	}
+}
+
+func TestKernelVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		release string
+		min     string
+		want    bool
+		wantErr bool
+	}{
+		{"5.15.0-1234-azure", "5.15", true, false},
+		{"5.15.0-1234-azure", "5.15.1", false, false},
+		{"5.15.153.1-microsoft-standard-WSL2", "5.10.16", true, false},
+		{"4.4.0-19041-Microsoft", "4.19", false, false},
+		{"3.10.0-1160.el7.x86_64", "3.10.0", true, false},
+		{"6.7-rc1", "6.6.9", true, false},
+		{"6.1.0+", "6.1.1", false, false},
+		{"10.0.0", "9.99.99", true, false},
+		{"5.4.0", "5.4.0", true, false},
+		{"banana", "5.4", false, true},
+		{"5.4.0", "five", false, true},
+	}
+
+	for _, tc := range tests {
+		got, err := KernelVersionAtLeast(tc.release, tc.min)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("KernelVersionAtLeast(%q, %q) = %v, %v, want %v (error: %v)", tc.release, tc.min, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestIsMuslLibc(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		commands map[string]string
+		want     bool
+	}{
+		{"musl loader", map[string]string{"/lib/ld-musl-x86_64.so.1": ""}, nil, true},
+		{"musl ldd", nil, map[string]string{"ldd --version": "musl libc (aarch64)\nVersion 1.2.4\nDynamic Program Loader\n"}, true},
+		{"glibc", map[string]string{"/lib64/ld-linux-x86-64.so.2": ""}, map[string]string{"ldd --version": "ldd (Ubuntu GLIBC 2.35-0ubuntu3.4) 2.35\nCopyright (C) 2022 Free Software Foundation, Inc.\n"}, false},
+		{"no ldd", nil, nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			fakeCommand(t, tc.commands)
+			if got := isMuslLibc(); got != tc.want {
+				t.Errorf("isMuslLibc() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInitSystem(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		commands map[string]string
+		want     string
+	}{
+		{"systemd", map[string]string{"/proc/1/comm": "systemd\n"}, nil, "systemd"},
+		{"systemd in a container", map[string]string{"/proc/1/comm": "entrypoint\n", "/run/systemd/system/.keep": ""}, nil, "systemd"},
+		{"openrc", map[string]string{"/proc/1/comm": "init\n", "/sbin/rc-service": "", "/run/openrc/softlevel": ""}, nil, "openrc"},
+		{"openrc init", map[string]string{"/proc/1/comm": "openrc-init\n"}, nil, "openrc"},
+		{"upstart", map[string]string{"/proc/1/comm": "init\n"}, map[string]string{"initctl --version": "initctl (upstart 1.12.1)\n"}, "upstart"},
+		{"sysvinit", map[string]string{"/proc/1/comm": "init\n"}, nil, "unknown"},
+		{"container shell", map[string]string{"/proc/1/comm": "bash\n"}, nil, "unknown"},
+		{"no proc", nil, nil, "unknown"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			fakeCommand(t, tc.commands)
+			if got := initSystem(); got != tc.want {
+				t.Errorf("initSystem() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSELinuxMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		commands map[string]string
+		want     string
+	}{
+		{"enforcing", map[string]string{"/sys/fs/selinux/enforce": "1"}, nil, "enforcing"},
+		{"permissive", map[string]string{"/sys/fs/selinux/enforce": "0"}, nil, "permissive"},
+		{"getenforce", nil, map[string]string{"getenforce": "Permissive\n"}, "permissive"},
+		{"disabled by getenforce", nil, map[string]string{"getenforce": "Disabled\n"}, "disabled"},
+		{"not installed", nil, nil, "disabled"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			fakeCommand(t, tc.commands)
+			if got := selinuxMode(); got != tc.want {
+				t.Errorf("selinuxMode() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppArmorEnabled(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{"enabled", map[string]string{"/sys/module/apparmor/parameters/enabled": "Y\n"}, true},
+		{"disabled", map[string]string{"/sys/module/apparmor/parameters/enabled": "N\n"}, false},
+		{"not loaded", nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			if got := appArmorEnabled(); got != tc.want {
+				t.Errorf("appArmorEnabled() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsChromeOSCrostini(t *testing.T) {
+	fakeHostname := func(t *testing.T, name string) {
+		orig := hostname
+		hostname = func() (string, error) { return name, nil }
+		t.Cleanup(func() { hostname = orig })
+	}
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		hostname string
+		env      string
+		want     bool
+	}{
+		{"milestone", map[string]string{"/dev/.cros_milestone": "120"}, "penguin", "", true},
+		{"penguin with sommelier", nil, "penguin", "0.20", true},
+		{"penguin without sommelier", nil, "penguin", "", false},
+		{"sommelier elsewhere", nil, "workstation", "0.20", false},
+		{"linux", nil, "workstation", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, tc.files)
+			fakeHostname(t, tc.hostname)
+			if tc.env != "" {
+				t.Setenv("SOMMELIER_VERSION", tc.env)
+			} else {
+				t.Setenv("SOMMELIER_VERSION", "")
+				os.Unsetenv("SOMMELIER_VERSION")
+			}
+			if got := isChromeOSCrostini(); got != tc.want {
+				t.Errorf("isChromeOSCrostini() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRaspberryPiModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"Raspberry Pi 3 Model B Plus Rev 1.3\x00", "Raspberry Pi 3 Model B Plus Rev 1.3"},
+		{"Raspberry Pi 4 Model B Rev 1.4\x00", "Raspberry Pi 4 Model B Rev 1.4"},
+		{"Raspberry Pi 5 Model B Rev 1.0\x00", "Raspberry Pi 5 Model B Rev 1.0"},
+		{"Pine64 RockPro64 v2.1\x00", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			fakeHostRoot(t, map[string]string{"/proc/device-tree/model": tc.model})
+			if got := raspberryPiModel(); got != tc.want {
+				t.Errorf("raspberryPiModel() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	fakeHostRoot(t, nil)
+	if got := raspberryPiModel(); got != "" {
+		t.Errorf("raspberryPiModel() = %q, want empty without a device tree", got)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// WindowsBuildNumber returns the build number of Windows, or an error wrapping ErrUnsupportedOS on other systems
+func WindowsBuildNumber() (int, error) {
+	return 0, fmt.Errorf("%w: %s is not Windows", ErrUnsupportedOS, runtime.GOOS)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// windowsBuildSource provides the Windows build number. It is an interface so that tests can stub the system calls.
+type windowsBuildSource interface {
+	// registryBuildNumber returns CurrentBuildNumber of the CurrentVersion registry key
+	registryBuildNumber() (string, error)
+	// kernelBuildNumber returns the build number reported by RtlGetVersion
+	kernelBuildNumber() (uint32, error)
+}
+
+type systemBuildSource struct{}
+
+func (systemBuildSource) registryBuildNumber() (string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+	v, _, err := k.GetStringValue("CurrentBuildNumber")
+	return v, err
+}
+
+func (systemBuildSource) kernelBuildNumber() (uint32, error) {
+	// unlike GetVersionEx, RtlGetVersion is not affected by the compatibility manifest of the binary
+	return windows.RtlGetVersion().BuildNumber, nil
+}
+
+var buildSource windowsBuildSource = systemBuildSource{}
+
+// WindowsBuildNumber returns the build number of Windows, such as 19045 for Windows 10 22H2
+func WindowsBuildNumber() (int, error) {
+	v, err := buildSource.registryBuildNumber()
+	if err == nil {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n, nil
+		}
+	}
+	n, kerr := buildSource.kernelBuildNumber()
+	if kerr != nil || n == 0 {
+		return 0, fmt.Errorf("unable to determine the Windows build number: registry: %v, kernel: %v", err, kerr)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeBuildSource struct {
+	registry    string
+	registryErr error
+	kernel      uint32
+	kernelErr   error
+}
+
+func (f fakeBuildSource) registryBuildNumber() (string, error) { return f.registry, f.registryErr }
+
+func (f fakeBuildSource) kernelBuildNumber() (uint32, error) { return f.kernel, f.kernelErr }
+
+func TestWindowsBuildNumber(t *testing.T) {
+	errMissing := errors.New("missing")
+	tests := []struct {
+		name    string
+		source  fakeBuildSource
+		want    int
+		wantErr bool
+	}{
+		{"registry", fakeBuildSource{registry: "19045", kernel: 19041}, 19045, false},
+		{"registry missing", fakeBuildSource{registryErr: errMissing, kernel: 22631}, 22631, false},
+		{"registry malformed", fakeBuildSource{registry: "22631.2861", kernel: 22631}, 22631, false},
+		{"unknown", fakeBuildSource{registryErr: errMissing, kernelErr: errMissing}, 0, true},
+	}
+
+	orig := buildSource
+	defer func() { buildSource = orig }()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buildSource = tc.source
+			got, err := WindowsBuildNumber()
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("WindowsBuildNumber() = %d, %v, want %d (error: %v)", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+// Detection overrides let end-to-end tests force what minikube detects about the host without faking hardware.
+// They only take effect when MINIKUBE_DETECT_OVERRIDES is "true", so that a stray variable cannot change the
+// behaviour of a regular install. The supported keys are:
+//
+//	MINIKUBE_FORCE_ARCH       the architecture reported by RuntimeArch and EffectiveArch, e.g. "arm64"
+//	MINIKUBE_FORCE_CLOUD      the Provider reported by CloudProvider, e.g. "gce", or "none" for no cloud
+//	MINIKUBE_FORCE_CONTAINER  whether IsInContainer reports true, as parsed by strconv.ParseBool
+//
+// Every override applied is logged, so that a forced result is visible in "minikube logs".
+const (
+	detectOverridesEnv = "MINIKUBE_DETECT_OVERRIDES"
+	forceArchEnv       = "MINIKUBE_FORCE_ARCH"
+	forceCloudEnv      = "MINIKUBE_FORCE_CLOUD"
+	forceContainerEnv  = "MINIKUBE_FORCE_CONTAINER"
+)
+
+// detectOverride returns the value forced through the env var key, if overrides are enabled and key is set
+func detectOverride(key string) (string, bool) {
+	if os.Getenv(detectOverridesEnv) != "true" {
+		return "", false
+	}
+	v := os.Getenv(key)
+	if v == "" {
+		return "", false
+	}
+	klog.Infof("detection overridden by %s=%q", key, v)
+	return v, true
+}
+
+// forcedArch returns the architecture forced by MINIKUBE_FORCE_ARCH
+func forcedArch() (string, bool) {
+	return detectOverride(forceArchEnv)
+}
+
+// forcedCloud returns the cloud provider forced by MINIKUBE_FORCE_CLOUD
+func forcedCloud() (Provider, bool) {
+	v, ok := detectOverride(forceCloudEnv)
+	if !ok {
+		return ProviderNone, false
+	}
+	if v == "none" {
+		return ProviderNone, true
+	}
+	return Provider(v), true
+}
+
+// forcedContainer returns whether MINIKUBE_FORCE_CONTAINER forces minikube to be seen inside a container or not
+func forcedContainer() (bool, bool) {
+	v, ok := detectOverride(forceContainerEnv)
+	if !ok {
+		return false, false
+	}
+	in, err := strconv.ParseBool(v)
+	if err != nil {
+		klog.Warningf("ignoring %s=%q: %v", forceContainerEnv, v, err)
+		return false, false
+	}
+	return in, true
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestDetectOverrides(t *testing.T) {
+	origProbes := cloudProbes
+	defer func() {
+		cloudProbes = origProbes
+		ResetCloudDetection()
+	}()
+	ResetCloudDetection()
+	cloudProbes = []cloudProbe{{ProviderAWS, DetectorFunc(func(context.Context) (bool, error) { return true, nil })}}
+	fakeHostRoot(t, map[string]string{"/.dockerenv": ""})
+	t.Setenv("container", "")
+
+	t.Setenv(forceArchEnv, "riscv64")
+	t.Setenv(forceCloudEnv, "gce")
+	t.Setenv(forceContainerEnv, "false")
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv(detectOverridesEnv, "")
+		if got := RuntimeArch(); got == "riscv64" && runtime.GOARCH != "riscv64" {
+			t.Errorf("RuntimeArch() = %q, overridden without %s", got, detectOverridesEnv)
+		}
+		if got := CloudProvider(); got != ProviderAWS {
+			t.Errorf("CloudProvider() = %q, want %q", got, ProviderAWS)
+		}
+		if runtime.GOOS == "linux" && !IsInContainer() {
+			t.Errorf("IsInContainer() = false, overridden without %s", detectOverridesEnv)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Setenv(detectOverridesEnv, "true")
+		if got := RuntimeArch(); got != "riscv64" {
+			t.Errorf("RuntimeArch() = %q, want %q", got, "riscv64")
+		}
+		if got := EffectiveArch(); got != "riscv64" {
+			t.Errorf("EffectiveArch() = %q, want %q", got, "riscv64")
+		}
+		// the override wins over the memoized detection
+		if got := CloudProvider(); got != ProviderGCE {
+			t.Errorf("CloudProvider() = %q, want %q", got, ProviderGCE)
+		}
+		if got := CloudProviderWithContext(context.Background()); got != ProviderGCE {
+			t.Errorf("CloudProviderWithContext() = %q, want %q", got, ProviderGCE)
+		}
+		if IsInContainer() {
+			t.Error("IsInContainer() = true, want the forced false")
+		}
+	})
+
+	t.Run("values", func(t *testing.T) {
+		t.Setenv(detectOverridesEnv, "true")
+		t.Setenv(forceCloudEnv, "none")
+		if got := CloudProvider(); got != ProviderNone {
+			t.Errorf("CloudProvider() = %q, want %q", got, ProviderNone)
+		}
+		t.Setenv(forceContainerEnv, "1")
+		if !IsInContainer() {
+			t.Error("IsInContainer() = false, want the forced true")
+		}
+		// unparsable values fall back to the real detection
+		t.Setenv(forceContainerEnv, "maybe")
+		if _, ok := forcedContainer(); ok {
+			t.Errorf("forcedContainer() applied the invalid %s=maybe", forceContainerEnv)
+		}
+	})
+}
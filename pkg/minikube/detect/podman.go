@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// podmanTimeout bounds how long the podman detectors wait for podman, which may have to reach a podman machine VM
+const podmanTimeout = 5 * time.Second
+
+var rootlessPodmanProbe = memoizedProbe{"rootless-podman"}
+
+// IsRootlessPodman returns true if podman runs in rootless mode.
+// The result is computed once per process.
+func IsRootlessPodman() bool {
+	rootless, _ := rootlessPodmanProbe.get(background(isRootlessPodman))
+	return rootless
+}
+
+func isRootlessPodman(ctx context.Context) (bool, error) {
+	o, err := runCommandTimeout(ctx, podmanTimeout, "podman", "info", "--format", "{{.Host.Security.Rootless}}")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.TrimSpace(string(o)))
+}
+
+// PodmanVersion returns the version of the podman client, e.g. "4.3.1"
+func PodmanVersion() (string, error) {
+	return PodmanVersionWithContext(context.Background())
+}
+
+// PodmanVersionWithContext is PodmanVersion, giving up when ctx is done
+func PodmanVersionWithContext(ctx context.Context) (string, error) {
+	o, err := runCommandTimeout(ctx, podmanTimeout, "podman", "version", "--format", "{{.Client.Version}}")
+	if err != nil {
+		return "", fmt.Errorf("podman version: %w", err)
+	}
+	v := strings.TrimSpace(string(o))
+	if v == "" {
+		return "", fmt.Errorf("podman version: no client version reported")
+	}
+	return v, nil
+}
+
+// PodmanCanBindPrivilegedPorts returns true if containers started by podman can publish ports below 1024,
+// either because podman runs as root or because the kernel lets unprivileged users bind them.
+func PodmanCanBindPrivilegedPorts() bool {
+	if _, err := PodmanVersion(); err != nil {
+		return false
+	}
+	if !IsRootlessPodman() {
+		return true
+	}
+	return unprivilegedPortStart() == 0
+}
+
+// unprivilegedPortStart returns the lowest port that unprivileged users may bind, as set by the
+// net.ipv4.ip_unprivileged_port_start sysctl, defaulting to 1024 when it is unavailable
+func unprivilegedPortStart() int {
+	b, err := os.ReadFile(hostPath("/proc/sys/net/ipv4/ip_unprivileged_port_start"))
+	if err != nil {
+		return 1024
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 1024
+	}
+	return n
+}
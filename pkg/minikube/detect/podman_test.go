@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIsRootlessPodman(t *testing.T) {
+	info := "podman info --format {{.Host.Security.Rootless}}"
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		want    bool
+	}{
+		{"rootless", map[string]string{info: "true\n"}, true},
+		{"rootful", map[string]string{info: "false\n"}, false},
+		{"podman missing", map[string]string{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeCommand(t, tc.outputs)
+			rootlessPodmanProbe.reset()
+			defer rootlessPodmanProbe.reset()
+			if got := IsRootlessPodman(); got != tc.want {
+				t.Errorf("IsRootlessPodman() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPodmanVersion(t *testing.T) {
+	version := "podman version --format {{.Client.Version}}"
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"installed", map[string]string{version: "4.3.1\n"}, "4.3.1", false},
+		{"empty", map[string]string{version: "\n"}, "", true},
+		{"podman missing", map[string]string{}, "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeCommand(t, tc.outputs)
+			got, err := PodmanVersion()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("PodmanVersion() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("PodmanVersion() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPodmanVersionWithContextDeadline(t *testing.T) {
+	orig := runCommandContext
+	t.Cleanup(func() { runCommandContext = orig })
+	runCommandContext = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := PodmanVersionWithContext(ctx); err == nil {
+		t.Error("PodmanVersionWithContext() succeeded for a hung podman")
+	}
+	if elapsed := time.Since(start); elapsed >= podmanTimeout {
+		t.Errorf("PodmanVersionWithContext() returned after %s, want it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestPodmanCanBindPrivilegedPorts(t *testing.T) {
+	version := "podman version --format {{.Client.Version}}"
+	info := "podman info --format {{.Host.Security.Rootless}}"
+	sysctl := "/proc/sys/net/ipv4/ip_unprivileged_port_start"
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		files   map[string]string
+		want    bool
+	}{
+		{"rootful", map[string]string{version: "4.3.1\n", info: "false\n"}, map[string]string{sysctl: "1024\n"}, true},
+		{"rootless", map[string]string{version: "4.3.1\n", info: "true\n"}, map[string]string{sysctl: "1024\n"}, false},
+		{"rootless with low ports allowed", map[string]string{version: "4.3.1\n", info: "true\n"}, map[string]string{sysctl: "0\n"}, true},
+		{"rootless without sysctl", map[string]string{version: "4.3.1\n", info: "true\n"}, map[string]string{}, false},
+		{"podman missing", map[string]string{}, map[string]string{sysctl: "0\n"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeCommand(t, tc.outputs)
+			fakeHostRoot(t, tc.files)
+			rootlessPodmanProbe.reset()
+			defer rootlessPodmanProbe.reset()
+			if got := PodmanCanBindPrivilegedPorts(); got != tc.want {
+				t.Errorf("PodmanCanBindPrivilegedPorts() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
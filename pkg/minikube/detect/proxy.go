@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// ProxySettings is the HTTP proxy configuration of the environment minikube runs in
+type ProxySettings struct {
+	HTTP    string
+	HTTPS   string
+	NoProxy string
+}
+
+// ProxyConfig returns the proxy configuration from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+// falling back to their lower case variants when the upper case ones are unset
+func ProxyConfig() ProxySettings {
+	return ProxySettings{
+		HTTP:    proxyEnv("HTTP_PROXY"),
+		HTTPS:   proxyEnv("HTTPS_PROXY"),
+		NoProxy: proxyEnv("NO_PROXY"),
+	}
+}
+
+func proxyEnv(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return os.Getenv(strings.ToLower(name))
+}
+
+// ShouldProxy returns true if requests to host, which may include a port, go through the proxy.
+// Loopback addresses are never proxied. NO_PROXY entries follow the semantics of Go's net/http:
+//   - "*" disables the proxy for every host
+//   - an IP address or CIDR block, e.g. "192.168.49.2" or "192.168.0.0/16", matches the addresses it covers
+//   - "example.com" matches example.com and its subdomains
+//   - ".example.com" and "*.example.com" only match subdomains of example.com
+//   - an entry with a port, e.g. "example.com:8443", only matches that port
+func (p ProxySettings) ShouldProxy(host string) bool {
+	if p.HTTP == "" && p.HTTPS == "" {
+		return false
+	}
+	host, port := splitHostPort(strings.ToLower(host))
+	if host == "" || host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return false
+	}
+
+	for _, entry := range strings.Split(strings.ToLower(p.NoProxy), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return false
+		}
+		if _, block, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && block.Contains(ip) {
+				return false
+			}
+			continue
+		}
+		entryHost, entryPort := splitHostPort(entry)
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return false
+			}
+			continue
+		}
+		if noProxyDomainMatch(host, entryHost) {
+			return false
+		}
+	}
+	return true
+}
+
+// noProxyDomainMatch returns true if host matches the NO_PROXY domain entry
+func noProxyDomainMatch(host, entry string) bool {
+	entry = strings.TrimPrefix(entry, "*")
+	if strings.HasPrefix(entry, ".") {
+		return strings.HasSuffix(host, entry)
+	}
+	return host == entry || strings.HasSuffix(host, "."+entry)
+}
+
+// splitHostPort splits an optional port off hostport, also accepting bracketed and bare IPv6 addresses
+func splitHostPort(hostport string) (host, port string) {
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		return h, p
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]"), ""
+}
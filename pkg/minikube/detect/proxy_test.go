@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import "testing"
+
+func TestProxyConfig(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("HTTP_PROXY", "http://proxy.corp:3128")
+	t.Setenv("https_proxy", "http://secure.corp:3129")
+	t.Setenv("no_proxy", "localhost,.corp")
+
+	want := ProxySettings{HTTP: "http://proxy.corp:3128", HTTPS: "http://secure.corp:3129", NoProxy: "localhost,.corp"}
+	if got := ProxyConfig(); got != want {
+		t.Errorf("ProxyConfig() = %+v, want %+v", got, want)
+	}
+}
+
+func TestShouldProxy(t *testing.T) {
+	proxied := func(noProxy string) ProxySettings {
+		return ProxySettings{HTTPS: "http://proxy.corp:3128", NoProxy: noProxy}
+	}
+	tests := []struct {
+		name     string
+		settings ProxySettings
+		host     string
+		want     bool
+	}{
+		{"no proxy configured", ProxySettings{NoProxy: "example.com"}, "registry.k8s.io", false},
+		{"not excluded", proxied(""), "registry.k8s.io", true},
+		{"localhost", proxied(""), "localhost:8443", false},
+		{"loopback ipv4", proxied(""), "127.0.0.1", false},
+		{"loopback ipv6", proxied(""), "[::1]:8443", false},
+		{"wildcard", proxied("*"), "registry.k8s.io", false},
+		{"exact domain", proxied("registry.k8s.io"), "registry.k8s.io", false},
+		{"domain matches subdomain", proxied("k8s.io"), "registry.k8s.io", false},
+		{"domain does not match suffix", proxied("k8s.io"), "notk8s.io", true},
+		{"leading dot matches subdomain", proxied(".k8s.io"), "registry.k8s.io", false},
+		{"leading dot does not match domain", proxied(".k8s.io"), "k8s.io", true},
+		{"star dot matches subdomain", proxied("*.k8s.io"), "registry.k8s.io", false},
+		{"case insensitive", proxied("Registry.K8s.IO"), "REGISTRY.k8s.io", false},
+		{"spaces around entries", proxied(" example.com , k8s.io "), "registry.k8s.io", false},
+		{"host with port", proxied("k8s.io"), "registry.k8s.io:443", false},
+		{"entry with matching port", proxied("k8s.io:443"), "registry.k8s.io:443", false},
+		{"entry with other port", proxied("k8s.io:443"), "registry.k8s.io:5000", true},
+		{"ip", proxied("192.168.49.2"), "192.168.49.2:8443", false},
+		{"other ip", proxied("192.168.49.2"), "192.168.49.3", true},
+		{"cidr", proxied("10.0.0.0/8,192.168.0.0/16"), "192.168.49.2", false},
+		{"outside cidr", proxied("192.168.0.0/16"), "172.17.0.2", true},
+		{"cidr ignores names", proxied("192.168.0.0/16"), "minikube", true},
+		{"ipv6 cidr", proxied("fd00::/8"), "[fd00::2]:8443", false},
+		{"empty entries", proxied(",,"), "registry.k8s.io", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.settings.ShouldProxy(tc.host); got != tc.want {
+				t.Errorf("%+v.ShouldProxy(%q) = %v, want %v", tc.settings, tc.host, got, tc.want)
+			}
+		})
+	}
+}
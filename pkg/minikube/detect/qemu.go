@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// qemuSystemBinaries maps an architecture to the QEMU binary emulating it
+var qemuSystemBinaries = map[string]string{
+	"amd64":   "qemu-system-x86_64",
+	"arm":     "qemu-system-arm",
+	"arm64":   "qemu-system-aarch64",
+	"ppc64le": "qemu-system-ppc64",
+	"riscv64": "qemu-system-riscv64",
+	"s390x":   "qemu-system-s390x",
+}
+
+// QEMUInstalled returns true if the QEMU binary for the effective architecture of the host is in PATH.
+// On Apple Silicon this requires qemu-system-aarch64, whatever the architecture of minikube itself.
+func QEMUInstalled() bool {
+	_, err := QEMUBinary()
+	return err == nil
+}
+
+// QEMUBinary returns the path of the QEMU binary for the effective architecture of the host, e.g. qemu-system-aarch64
+func QEMUBinary() (string, error) {
+	return qemuBinary(EffectiveArch())
+}
+
+// QEMUVersion returns the version of the QEMU binary for the effective architecture of the host, e.g. "7.1.0"
+func QEMUVersion() (string, error) {
+	return qemuVersion(EffectiveArch())
+}
+
+func qemuBinary(arch string) (string, error) {
+	name, ok := qemuSystemBinaries[arch]
+	if !ok {
+		return "", fmt.Errorf("%w: no QEMU system emulator for %s", ErrUnsupportedArch, arch)
+	}
+	p, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s is not installed: %w", name, err)
+	}
+	return p, nil
+}
+
+func qemuVersion(arch string) (string, error) {
+	bin, err := qemuBinary(arch)
+	if err != nil {
+		return "", err
+	}
+	// e.g. "QEMU emulator version 7.1.0\nCopyright (c) 2003-2022 Fabrice Bellard and the QEMU Project developers"
+	o, err := runCommand(bin, "--version")
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", bin, err)
+	}
+	return parseToolVersion("qemu", string(o))
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeExecutables creates empty executables with the given names in a new directory, and makes it the only one in PATH
+func fakeExecutables(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("failed to write fake %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestQEMUVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		arch      string
+		installed []string
+		version   string
+		want      string
+		wantErr   bool
+	}{
+		{"amd64", "amd64", []string{"qemu-system-x86_64"}, "QEMU emulator version 7.1.0\nCopyright (c) 2003-2022 Fabrice Bellard and the QEMU Project developers\n", "7.1.0", false},
+		{"arm64", "arm64", []string{"qemu-system-aarch64", "qemu-system-x86_64"}, "QEMU emulator version 7.2.0 (Debian 1:7.2+dfsg-1)\n", "7.2.0", false},
+		{"arm64 with only x86 qemu", "arm64", []string{"qemu-system-x86_64"}, "", "", true},
+		{"s390x", "s390x", []string{"qemu-system-s390x"}, "QEMU emulator version 6.2.0\n", "6.2.0", false},
+		{"arm", "arm", []string{"qemu-system-arm"}, "QEMU emulator version 7.2.0 (Debian 1:7.2+dfsg-1)\n", "7.2.0", false},
+		{"riscv64", "riscv64", []string{"qemu-system-riscv64"}, "QEMU emulator version 8.0.0\n", "8.0.0", false},
+		{"unsupported arch", "mips64le", []string{"qemu-system-mips64el"}, "", "", true},
+		{"unparsable", "amd64", []string{"qemu-system-x86_64"}, "qemu: unknown option\n", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := fakeExecutables(t, tc.installed...)
+			outputs := map[string]string{}
+			if name, ok := qemuSystemBinaries[tc.arch]; ok && tc.version != "" {
+				if runtime.GOOS == "windows" {
+					name += ".exe"
+				}
+				outputs[filepath.Join(dir, name)+" --version"] = tc.version
+			}
+			fakeCommand(t, outputs)
+
+			got, err := qemuVersion(tc.arch)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("qemuVersion(%q) error = %v, wantErr %v", tc.arch, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("qemuVersion(%q) = %q, want %q", tc.arch, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQEMUBinary(t *testing.T) {
+	dir := fakeExecutables(t, "qemu-system-aarch64")
+	got, err := qemuBinary("arm64")
+	if err != nil {
+		t.Fatalf("qemuBinary(arm64) error = %v", err)
+	}
+	if filepath.Dir(got) != dir {
+		t.Errorf("qemuBinary(arm64) = %q, want it in %q", got, dir)
+	}
+	if _, err := qemuBinary("amd64"); err == nil {
+		t.Error("qemuBinary(amd64) succeeded without qemu-system-x86_64")
+	}
+	if _, err := qemuBinary("mips64"); !errors.Is(err, ErrUnsupportedArch) {
+		t.Errorf("qemuBinary(mips64) error = %v, want ErrUnsupportedArch", err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// registryTimeout bounds each check of ReachableRegistries
+const registryTimeout = 5 * time.Second
+
+var (
+	// preflightRegistries are the registries ReachableRegistries checks, those minikube pulls its images from.
+	// It is a variable so that tests can point it at local servers.
+	preflightRegistries = []string{"registry.k8s.io", "gcr.io", "docker.io"}
+
+	// registryEndpoints maps registries to the host serving their API, when it differs
+	registryEndpoints = map[string]string{"docker.io": "registry-1.docker.io"}
+
+	// registryTransport sends the requests of RegistryReachable, nil meaning http.DefaultTransport,
+	// which honors the proxy settings. It is a variable so that tests can trust their own server.
+	registryTransport http.RoundTripper
+)
+
+// RegistryReachable returns true if the registry API at https://<registry>/v2/ answers within timeout, either
+// with 200 or with the 401 that registries requiring authentication answer anonymous requests with.
+// Network errors are returned along with false.
+func RegistryReachable(registry string, timeout time.Duration) (bool, error) {
+	return RegistryReachableWithContext(context.Background(), registry, timeout)
+}
+
+// RegistryReachableWithContext is RegistryReachable, giving up when ctx is done.
+func RegistryReachableWithContext(ctx context.Context, registry string, timeout time.Duration) (bool, error) {
+	host := registry
+	if e, ok := registryEndpoints[registry]; ok {
+		host = e
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/v2/", nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := (&http.Client{Transport: registryTransport}).Do(req)
+	if err != nil {
+		return false, fmt.Errorf("checking registry %s: %w", registry, err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusUnauthorized:
+		return true, nil
+	}
+	return false, fmt.Errorf("checking registry %s: unexpected status %s", registry, resp.Status)
+}
+
+// ReachableRegistries checks in parallel whether each registry minikube pulls images from is reachable
+func ReachableRegistries() map[string]bool {
+	return ReachableRegistriesWithContext(context.Background())
+}
+
+// ReachableRegistriesWithContext is ReachableRegistries, giving up on the checks still running when ctx is done.
+func ReachableRegistriesWithContext(ctx context.Context) map[string]bool {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	reachable := map[string]bool{}
+	for _, r := range preflightRegistries {
+		wg.Add(1)
+		go func(r string) {
+			defer wg.Done()
+			ok, err := RegistryReachableWithContext(ctx, r, registryTimeout)
+			if err != nil {
+				klog.Infof("registry %s is not reachable: %v", r, err)
+			}
+			mu.Lock()
+			reachable[r] = ok
+			mu.Unlock()
+		}(r)
+	}
+	wg.Wait()
+	return reachable
+}
+
+// registryWarnings warns when none of the registries minikube pulls images from is reachable, typically in
+// air-gapped networks or behind the Great Firewall, suggesting a mirror
+func registryWarnings(ctx context.Context) []Warning {
+	reachable := ReachableRegistriesWithContext(ctx)
+	if ctx.Err() != nil {
+		// a canceled check says nothing about the network
+		return nil
+	}
+	var unreachable []string
+	for _, r := range preflightRegistries {
+		if !reachable[r] {
+			unreachable = append(unreachable, r)
+		}
+	}
+	if len(unreachable) == 0 || len(unreachable) != len(preflightRegistries) {
+		return nil
+	}
+	return []Warning{{
+		Detector:    "registries",
+		Severity:    SeverityError,
+		Message:     fmt.Sprintf("none of the container registries minikube pulls images from is reachable: %s", strings.Join(unreachable, ", ")),
+		Remediation: "configure a proxy, or pull through a mirror with --image-mirror-country or --image-repository",
+	}}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRegistry starts a TLS server answering /v2/ with status, trusted by RegistryReachable, and returns its address
+func fakeRegistry(t *testing.T, status int) string {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	orig := registryTransport
+	registryTransport = srv.Client().Transport
+	t.Cleanup(func() { registryTransport = orig })
+	return srv.Listener.Addr().String()
+}
+
+func TestRegistryReachable(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   bool
+	}{
+		{"ok", http.StatusOK, true},
+		{"auth required", http.StatusUnauthorized, true},
+		{"not a registry", http.StatusNotFound, false},
+		{"server error", http.StatusServiceUnavailable, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := fakeRegistry(t, tc.status)
+			got, err := RegistryReachable(addr, time.Second)
+			if got != tc.want {
+				t.Errorf("RegistryReachable() = %t, %v; want %t", got, err, tc.want)
+			}
+			if !got && err == nil {
+				t.Error("RegistryReachable() did not explain why the registry is unreachable")
+			}
+		})
+	}
+}
+
+func TestReachableRegistries(t *testing.T) {
+	orig := preflightRegistries
+	defer func() { preflightRegistries = orig }()
+
+	up := fakeRegistry(t, http.StatusUnauthorized)
+	// nothing listens on port 1
+	down := "127.0.0.1:1"
+
+	preflightRegistries = []string{up, down}
+	got := ReachableRegistries()
+	if !got[up] || got[down] || len(got) != 2 {
+		t.Errorf("ReachableRegistries() = %v, want only %s reachable", got, up)
+	}
+	if ws := registryWarnings(context.Background()); len(ws) != 0 {
+		t.Errorf("registryWarnings() = %+v while a registry is reachable, want none", ws)
+	}
+
+	preflightRegistries = []string{down}
+	if got := ReachableRegistries(); got[down] {
+		t.Errorf("ReachableRegistries() = %v, want %s unreachable", got, down)
+	}
+	ws := registryWarnings(context.Background())
+	if len(ws) != 1 || ws[0].Detector != "registries" || !strings.Contains(ws[0].Message, down) {
+		t.Errorf("registryWarnings() = %+v, want a warning naming %s", ws, down)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	preflightRegistries = []string{up}
+	if got := ReachableRegistriesWithContext(ctx); got[up] {
+		t.Errorf("ReachableRegistriesWithContext() = %v after cancel, want %s unchecked", got, up)
+	}
+	if ws := registryWarnings(ctx); len(ws) != 0 {
+		t.Errorf("registryWarnings() = %+v after cancel, want none", ws)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	// tlsInterceptionTarget is the endpoint HasTLSInterception connects to. It is a variable so that tests can override it.
+	tlsInterceptionTarget = "registry-1.docker.io:443"
+
+	// tlsInterceptionRoots are the CAs the chain of tlsInterceptionTarget is verified against, nil meaning the system ones
+	tlsInterceptionRoots *x509.CertPool
+
+	// publicRootOrganizations are the organizations of the public root CAs that container registries are known to chain to
+	publicRootOrganizations = map[string]bool{
+		"Amazon":                           true,
+		"Baltimore":                        true,
+		"COMODO CA Limited":                true,
+		"DigiCert Inc":                     true,
+		"Entrust, Inc.":                    true,
+		"GlobalSign":                       true,
+		"GlobalSign nv-sa":                 true,
+		"GoDaddy.com, Inc.":                true,
+		"Google Trust Services LLC":        true,
+		"IdenTrust":                        true,
+		"Internet Security Research Group": true,
+		"Microsoft Corporation":            true,
+		"Sectigo Limited":                  true,
+		"Starfield Technologies, Inc.":     true,
+		"The Go Daddy Group, Inc.":         true,
+		"The USERTRUST Network":            true,
+	}
+)
+
+// tlsInterceptionTimeout bounds the connection and handshake of HasTLSInterception
+const tlsInterceptionTimeout = 5 * time.Second
+
+// HasTLSInterception returns true if a TLS connection to a public registry presents a certificate chain that does not
+// root to a public CA, which means that a corporate proxy intercepts TLS and injects its own certificates.
+// Image pulls from inside minikube then fail unless that CA is installed in the cluster.
+// The connection goes through HTTPS_PROXY when ProxyConfig proxies the registry.
+// Network errors report false, as they do not show an interception.
+func HasTLSInterception() bool {
+	return tlsIntercepted(context.Background(), tlsInterceptionTarget, tlsInterceptionRoots)
+}
+
+// HasTLSInterceptionWithContext is HasTLSInterception, giving up when ctx is done
+func HasTLSInterceptionWithContext(ctx context.Context) bool {
+	return tlsIntercepted(ctx, tlsInterceptionTarget, tlsInterceptionRoots)
+}
+
+func tlsIntercepted(ctx context.Context, addr string, roots *x509.CertPool) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		klog.Warningf("invalid TLS interception target %q: %v", addr, err)
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, tlsInterceptionTimeout)
+	defer cancel()
+	nc, err := dialThroughProxy(ctx, addr)
+	if err != nil {
+		klog.Infof("unable to check for TLS interception, connecting to %s failed: %v", addr, err)
+		return false
+	}
+	defer nc.Close()
+	// the chain is verified below, to tell an unknown CA apart from other failures
+	conn := tls.Client(nc, &tls.Config{ServerName: host, InsecureSkipVerify: true}) //nolint:gosec
+	if err := conn.HandshakeContext(ctx); err != nil {
+		klog.Infof("unable to check for TLS interception, the TLS handshake with %s failed: %v", addr, err)
+		return false
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: intermediates})
+	if err != nil {
+		var unknown x509.UnknownAuthorityError
+		if errors.As(err, &unknown) {
+			klog.Infof("%s presented a certificate signed by an unknown authority: %v", addr, err)
+			return true
+		}
+		klog.Infof("unable to check for TLS interception, verifying the certificate of %s failed: %v", addr, err)
+		return false
+	}
+	for _, chain := range chains {
+		root := chain[len(chain)-1]
+		for _, org := range root.Subject.Organization {
+			if publicRootOrganizations[org] {
+				return false
+			}
+		}
+	}
+	klog.Infof("%s presented a certificate chain rooted at the non-public CA %q", addr, chains[0][len(chains[0])-1].Subject)
+	return true
+}
+
+// dialThroughProxy connects to addr, tunneling through HTTPS_PROXY with CONNECT when ProxyConfig says so,
+// as the proxy is where the interception happens
+func dialThroughProxy(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	p := ProxyConfig()
+	if p.HTTPS == "" || !p.ShouldProxy(addr) {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	proxy, err := url.Parse(p.HTTPS)
+	if err != nil || proxy.Host == "" {
+		// like net/http, accept a proxy given without a scheme
+		if proxy, err = url.Parse("http://" + p.HTTPS); err != nil {
+			return nil, fmt.Errorf("invalid HTTPS_PROXY %q: %w", p.HTTPS, err)
+		}
+	}
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		port := "80"
+		if proxy.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), port)
+	}
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to proxy %s: %w", proxyAddr, err)
+	}
+	if proxy.Scheme == "https" {
+		tc := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("connecting to proxy %s: %w", proxyAddr, err)
+		}
+		conn = tc
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: addr}, Host: addr, Header: http.Header{}}
+	if u := proxy.User; u != nil {
+		pass, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+pass)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sending CONNECT to proxy %s: %w", proxyAddr, err)
+	}
+	// the target only speaks after the ClientHello, so the reader buffers nothing past the answer
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading CONNECT answer of proxy %s: %w", proxyAddr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", proxyAddr, addr, resp.Status)
+	}
+	return conn, nil
+}
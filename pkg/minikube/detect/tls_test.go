@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSIntercepted(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+
+	// httptest certificates are self-signed by "Acme Co", standing in for a corporate CA
+	trusted := x509.NewCertPool()
+	trusted.AddCert(srv.Certificate())
+
+	t.Run("unknown authority", func(t *testing.T) {
+		if !tlsIntercepted(context.Background(), addr, x509.NewCertPool()) {
+			t.Error("tlsIntercepted() = false for a certificate signed by an unknown CA")
+		}
+	})
+	t.Run("trusted private CA", func(t *testing.T) {
+		if !tlsIntercepted(context.Background(), addr, trusted) {
+			t.Error("tlsIntercepted() = false for a chain rooted at a private CA")
+		}
+	})
+	t.Run("public CA", func(t *testing.T) {
+		orig := publicRootOrganizations
+		t.Cleanup(func() { publicRootOrganizations = orig })
+		publicRootOrganizations = map[string]bool{"Acme Co": true}
+		if tlsIntercepted(context.Background(), addr, trusted) {
+			t.Error("tlsIntercepted() = true for a chain rooted at a public CA")
+		}
+	})
+	t.Run("through the proxy", func(t *testing.T) {
+		// loopback addresses are never proxied, so the target is named after the host of the httptest certificate
+		var connected string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			connected = r.Host
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			defer upstream.Close()
+			client, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer client.Close()
+			if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+				return
+			}
+			go io.Copy(upstream, buf)
+			io.Copy(client, upstream)
+		}))
+		defer proxy.Close()
+		t.Setenv("HTTPS_PROXY", proxy.URL)
+		t.Setenv("NO_PROXY", "")
+
+		if !tlsIntercepted(context.Background(), "example.com:443", trusted) {
+			t.Error("tlsIntercepted() = false for a chain rooted at a private CA behind the proxy")
+		}
+		if connected != "example.com:443" {
+			t.Errorf("proxy got CONNECT %q, want example.com:443", connected)
+		}
+	})
+	t.Run("network error", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		closed := l.Addr().String()
+		l.Close()
+		if tlsIntercepted(context.Background(), closed, nil) {
+			t.Error("tlsIntercepted() = true when the connection fails")
+		}
+	})
+}
+
+func TestHasTLSInterception(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	origTarget, origRoots := tlsInterceptionTarget, tlsInterceptionRoots
+	t.Cleanup(func() { tlsInterceptionTarget, tlsInterceptionRoots = origTarget, origRoots })
+	tlsInterceptionTarget = srv.Listener.Addr().String()
+	tlsInterceptionRoots = x509.NewCertPool()
+	tlsInterceptionRoots.AddCert(srv.Certificate())
+
+	if !HasTLSInterception() {
+		t.Error("HasTLSInterception() = false behind an intercepting endpoint")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/spf13/viper"
+)
+
+// vfkitPathConfigKey is the viper key of the vfkit binary, which defaults to the one found in PATH
+const vfkitPathConfigKey = "vfkit-path"
+
+// VfkitInstalled returns true if the vfkit binary used by the vfkit driver is available on macOS
+func VfkitInstalled() bool {
+	if runtime.GOOS != "darwin" {
+		return false
+	}
+	_, err := vfkitBinary()
+	return err == nil
+}
+
+// VfkitVersion returns the version of the installed vfkit, e.g. "0.5.0"
+func VfkitVersion() (string, error) {
+	if runtime.GOOS != "darwin" {
+		return "", fmt.Errorf("%w: vfkit is only available on macOS", ErrUnsupportedOS)
+	}
+	return vfkitVersion()
+}
+
+func vfkitVersion() (string, error) {
+	bin, err := vfkitBinary()
+	if err != nil {
+		return "", err
+	}
+	o, err := runCommand(bin, "--version")
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", bin, err)
+	}
+	return parseToolVersion("vfkit", string(o))
+}
+
+// vfkitBinary returns the path of the vfkit binary: the "vfkit-path" setting if any, else vfkit in PATH
+func vfkitBinary() (string, error) {
+	if p := viper.GetString(vfkitPathConfigKey); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("vfkit is not installed at %s: %w", p, err)
+		}
+		return p, nil
+	}
+	p, err := exec.LookPath("vfkit")
+	if err != nil {
+		return "", fmt.Errorf("vfkit is not installed: %w", err)
+	}
+	return p, nil
+}
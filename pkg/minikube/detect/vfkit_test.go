@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestVfkitVersion(t *testing.T) {
+	dir := t.TempDir()
+	configured := filepath.Join(dir, "custom", "vfkit")
+	if err := os.MkdirAll(filepath.Dir(configured), 0755); err != nil {
+		t.Fatalf("failed to create fixture dir: %v", err)
+	}
+	if err := os.WriteFile(configured, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake vfkit: %v", err)
+	}
+	defer viper.Reset()
+
+	t.Run("configured path", func(t *testing.T) {
+		viper.Set(vfkitPathConfigKey, configured)
+		fakeCommand(t, map[string]string{configured + " --version": "vfkit version: v0.5.0\n"})
+		got, err := vfkitVersion()
+		if err != nil {
+			t.Fatalf("vfkitVersion() error = %v", err)
+		}
+		if got != "0.5.0" {
+			t.Errorf("vfkitVersion() = %q, want %q", got, "0.5.0")
+		}
+	})
+	t.Run("missing configured path", func(t *testing.T) {
+		viper.Set(vfkitPathConfigKey, filepath.Join(dir, "missing"))
+		if _, err := vfkitVersion(); err == nil {
+			t.Error("vfkitVersion() succeeded for a missing binary")
+		}
+	})
+	t.Run("path lookup", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("vfkit is not an executable name on windows")
+		}
+		viper.Set(vfkitPathConfigKey, "")
+		t.Setenv("PATH", filepath.Dir(configured))
+		fakeCommand(t, map[string]string{configured + " --version": "vfkit version: v0.1.1\n"})
+		got, err := vfkitVersion()
+		if err != nil {
+			t.Fatalf("vfkitVersion() error = %v", err)
+		}
+		if got != "0.1.1" {
+			t.Errorf("vfkitVersion() = %q, want %q", got, "0.1.1")
+		}
+	})
+	t.Run("not installed", func(t *testing.T) {
+		viper.Set(vfkitPathConfigKey, "")
+		t.Setenv("PATH", dir)
+		if _, err := vfkitVersion(); err == nil {
+			t.Error("vfkitVersion() succeeded without vfkit in PATH")
+		}
+	})
+}
+
+func TestVfkitUnsupported(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("vfkit is supported on macOS")
+	}
+	if VfkitInstalled() {
+		t.Error("VfkitInstalled() = true off macOS")
+	}
+	if _, err := VfkitVersion(); !errors.Is(err, ErrUnsupportedOS) {
+		t.Errorf("VfkitVersion() error = %v, want ErrUnsupportedOS", err)
+	}
+}
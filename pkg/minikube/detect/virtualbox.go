@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// VirtualBoxInstalled returns true if VBoxManage, which the virtualbox driver drives VMs with, is found
+func VirtualBoxInstalled() bool {
+	_, err := vboxManageBinary()
+	return err == nil
+}
+
+// VirtualBoxVersion returns the version of the installed VirtualBox without its build suffix,
+// e.g. "7.0.4" for "7.0.4r154605"
+func VirtualBoxVersion() (string, error) {
+	bin, err := vboxManageBinary()
+	if err != nil {
+		return "", err
+	}
+	o, err := runCommand(bin, "--version")
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", bin, err)
+	}
+	return parseToolVersion("VBoxManage", string(o))
+}
+
+// vboxManageBinary returns the path of VBoxManage, which the windows installer does not add to PATH
+func vboxManageBinary() (string, error) {
+	var dirs []string
+	switch runtime.GOOS {
+	case "windows":
+		dirs = []string{os.Getenv("VBOX_INSTALL_PATH"), os.Getenv("VBOX_MSI_INSTALL_PATH"), filepath.Join(os.Getenv("ProgramFiles"), "Oracle", "VirtualBox")}
+	case "darwin":
+		dirs = []string{"/Applications/VirtualBox.app/Contents/MacOS"}
+	}
+	return findBinary("VBoxManage", dirs...)
+}
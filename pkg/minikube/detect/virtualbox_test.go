@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeBinaryPath returns the path of the fake executable created by fakeExecutables in dir
+func fakeBinaryPath(dir, name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(dir, name)
+}
+
+func TestVirtualBoxVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"release", "7.0.4r154605\n", "7.0.4", false},
+		{"distribution build", "6.1.38_Ubuntur153438\n", "6.1.38", false},
+		{"kernel module warning", "WARNING: The vboxdrv kernel module is not loaded.\n6.1.40r154048\n", "6.1.40", false},
+		{"unparsable", "VBoxManage: error: unknown option\n", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := fakeExecutables(t, "VBoxManage")
+			fakeCommand(t, map[string]string{fakeBinaryPath(dir, "VBoxManage") + " --version": tc.version})
+			if !VirtualBoxInstalled() {
+				t.Fatal("VirtualBoxInstalled() = false with VBoxManage in PATH")
+			}
+			got, err := VirtualBoxVersion()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("VirtualBoxVersion() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("VirtualBoxVersion() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVirtualBoxNotInstalled(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("VirtualBox may be installed outside of PATH")
+	}
+	fakeExecutables(t)
+	if VirtualBoxInstalled() {
+		t.Error("VirtualBoxInstalled() = true without VBoxManage")
+	}
+	if _, err := VirtualBoxVersion(); err == nil {
+		t.Error("VirtualBoxVersion() succeeded without VBoxManage")
+	}
+}
+
+func TestFindBinary(t *testing.T) {
+	preferred := fakeExecutables(t, "tool")
+	inPath := fakeExecutables(t, "tool")
+
+	got, err := findBinary("tool", filepath.Join(preferred, "missing"), preferred)
+	if err != nil {
+		t.Fatalf("findBinary() error = %v", err)
+	}
+	if filepath.Dir(got) != preferred {
+		t.Errorf("findBinary() = %q, want it in %q", got, preferred)
+	}
+	got, err = findBinary("tool", "")
+	if err != nil {
+		t.Fatalf("findBinary() error = %v", err)
+	}
+	if filepath.Dir(got) != inPath {
+		t.Errorf("findBinary() = %q, want it in PATH dir %q", got, inPath)
+	}
+	if _, err := findBinary("other"); err == nil {
+		t.Error("findBinary() succeeded for a missing binary")
+	}
+}
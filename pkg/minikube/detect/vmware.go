@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// VMwareInstalled returns true if vmrun, which the vmware driver drives VMs with, is found
+func VMwareInstalled() bool {
+	_, err := vmrunBinary()
+	return err == nil
+}
+
+// VMwareVersion returns the version of vmrun without its build suffix, e.g. "1.17.0" for "vmrun version 1.17.0 build-20800274".
+// vmrun is versioned along with VMware Workstation and Fusion, e.g. 1.17.0 ships with Workstation 17.
+func VMwareVersion() (string, error) {
+	bin, err := vmrunBinary()
+	if err != nil {
+		return "", err
+	}
+	// without arguments vmrun prints its usage, starting with its version, and exits with an error
+	o, err := runCommand(bin)
+	v, perr := parseToolVersion("vmrun", string(o))
+	if perr == nil {
+		return v, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", bin, err)
+	}
+	return "", perr
+}
+
+// vmrunBinary returns the path of vmrun, which the VMware installers for macOS and windows do not add to PATH
+func vmrunBinary() (string, error) {
+	var dirs []string
+	switch runtime.GOOS {
+	case "windows":
+		dirs = []string{filepath.Join(os.Getenv("ProgramFiles(x86)"), "VMware", "VMware Workstation"), filepath.Join(os.Getenv("ProgramFiles"), "VMware", "VMware Workstation")}
+	case "darwin":
+		dirs = []string{"/Applications/VMware Fusion.app/Contents/Public"}
+	}
+	return findBinary("vmrun", dirs...)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestVMwareVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   string
+		want    string
+		wantErr bool
+	}{
+		{"workstation 17", "\nvmrun version 1.17.0 build-20800274\n\nUsage: vmrun [AUTHENTICATION-FLAGS] COMMAND [PARAMETERS]\n", "1.17.0", false},
+		{"fusion 12", "vmrun version 1.16.0 build-17801498\n", "1.16.0", false},
+		{"unparsable", "Usage: vmrun COMMAND\n", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := fakeExecutables(t, "vmrun")
+			fakeCommand(t, map[string]string{fakeBinaryPath(dir, "vmrun"): tc.usage})
+			if !VMwareInstalled() {
+				t.Fatal("VMwareInstalled() = false with vmrun in PATH")
+			}
+			got, err := VMwareVersion()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("VMwareVersion() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("VMwareVersion() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVMwareNotInstalled(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("VMware may be installed outside of PATH")
+	}
+	fakeExecutables(t)
+	if VMwareInstalled() {
+		t.Error("VMwareInstalled() = true without vmrun")
+	}
+	if _, err := VMwareVersion(); err == nil {
+		t.Error("VMwareVersion() succeeded without vmrun")
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Severity ranks how likely a detection warning is to break a cluster
+type Severity int
+
+const (
+	// SeverityInfo is a setting worth knowing about that minikube copes with
+	SeverityInfo Severity = iota
+	// SeverityWarning degrades the cluster or makes some features fail
+	SeverityWarning
+	// SeverityError is likely to make the cluster fail to start
+	SeverityError
+)
+
+// String returns the name of s, as printed in pre-flight advisories
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Warning is a problem with the host found by a detector
+type Warning struct {
+	// Detector names the check that found the problem, e.g. "swap"
+	Detector string
+	Severity Severity
+	Message  string
+	// Remediation tells the user how to fix the problem, or "" if there is no known fix
+	Remediation string
+}
+
+const (
+	// kubeadmMinMemoryMiB is the memory kubeadm refuses to start Kubernetes with less of
+	kubeadmMinMemoryMiB = 1800
+	// kubeadmMinCPUs is the CPU count kubeadm refuses to start Kubernetes with less of
+	kubeadmMinCPUs = 2
+)
+
+// warningCheck reports the problems one detector finds with the host
+type warningCheck func(ctx context.Context) []Warning
+
+// warningChecks are the checks DetectionWarnings runs. It is a variable so that tests can stub it.
+var warningChecks = []warningCheck{
+	memoryWarnings,
+	swapWarnings,
+	selinuxWarnings,
+	kvmWarnings,
+	dockerDesktopWarnings,
+	homeFilesystemWarnings,
+	caseInsensitiveCacheWarnings,
+	registryWarnings,
+}
+
+// DetectionWarnings runs the checks for problems with the host, such as swap being enabled or too little memory,
+// and returns the problems found, the most severe first. Callers such as pre-flight checks can print them as advisories.
+func DetectionWarnings() []Warning {
+	return DetectionWarningsWithContext(context.Background())
+}
+
+// DetectionWarningsWithContext is DetectionWarnings, passing ctx to the checks that query other programs or the network
+func DetectionWarningsWithContext(ctx context.Context) []Warning {
+	// the checks run in parallel, each into its own slot so that the order of the result does not depend on timing
+	found := make([][]Warning, len(warningChecks))
+	var wg sync.WaitGroup
+	for i, check := range warningChecks {
+		wg.Add(1)
+		go func(i int, check warningCheck) {
+			defer wg.Done()
+			found[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	ws := []Warning{}
+	for _, f := range found {
+		ws = appendWarnings(ws, f...)
+	}
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].Severity > ws[j].Severity })
+	return ws
+}
+
+// appendWarnings appends the warnings to ws, skipping the ones whose detector already reported the same problem
+func appendWarnings(ws []Warning, add ...Warning) []Warning {
+	for _, w := range add {
+		dup := false
+		for _, o := range ws {
+			if o.Detector == w.Detector && o.Message == w.Message {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestWarningChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		linux    bool
+		setup    func(t *testing.T)
+		check    warningCheck
+		detector string
+		severity Severity
+	}{
+		{
+			name:  "swap",
+			linux: true,
+			setup: func(t *testing.T) {
+				fakeHostRoot(t, map[string]string{"/proc/swaps": "Filename Type Size Used Priority\n/swapfile file 2097148 0 -2\n"})
+			},
+			check:    swapWarnings,
+			detector: "swap",
+			severity: SeverityInfo,
+		},
+		{
+			name:  "selinux enforcing",
+			linux: true,
+			setup: func(t *testing.T) {
+				fakeHostRoot(t, map[string]string{"/sys/fs/selinux/enforce": "1"})
+			},
+			check:    selinuxWarnings,
+			detector: "selinux",
+			severity: SeverityWarning,
+		},
+		{
+			name:  "low memory",
+			linux: true,
+			setup: func(t *testing.T) {
+				fakeHostRoot(t, map[string]string{"/proc/meminfo": "MemTotal: 2048000 kB\nMemAvailable: 1024000 kB\n"})
+				t.Setenv("container", "")
+			},
+			check:    memoryWarnings,
+			detector: "memory",
+			severity: SeverityWarning,
+		},
+		{
+			name:  "kvm permission denied",
+			linux: true,
+			setup: func(t *testing.T) {
+				if os.Geteuid() == 0 {
+					t.Skip("root bypasses file permissions")
+				}
+				root := fakeHostRoot(t, map[string]string{"/dev/kvm": ""})
+				if err := os.Chmod(filepath.Join(root, "dev", "kvm"), 0); err != nil {
+					t.Fatalf("failed to restrict the fake /dev/kvm: %v", err)
+				}
+			},
+			check:    kvmWarnings,
+			detector: "kvm",
+			severity: SeverityError,
+		},
+		{
+			name: "docker desktop resources",
+			setup: func(t *testing.T) {
+				fakeCommand(t, map[string]string{
+					"docker info --format {{json .}}": `{"Name":"docker-desktop","OperatingSystem":"Docker Desktop","NCPU":1,"MemTotal":1073741824}`,
+				})
+			},
+			check:    dockerDesktopWarnings,
+			detector: "docker-desktop",
+			severity: SeverityError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.linux && runtime.GOOS != "linux" {
+				t.Skip("only detected on linux")
+			}
+			tc.setup(t)
+
+			ws := tc.check(context.Background())
+			if len(ws) == 0 {
+				t.Fatalf("check found no problem, want a %s warning", tc.detector)
+			}
+			for _, w := range ws {
+				if w.Detector != tc.detector || w.Severity != tc.severity || w.Message == "" || w.Remediation == "" {
+					t.Errorf("check reported %+v, want %s warnings of severity %s with a remediation", w, tc.detector, tc.severity)
+				}
+			}
+		})
+	}
+}
+
+func TestWarningChecksHealthyHost(t *testing.T) {
+	fakeHostRoot(t, map[string]string{
+		"/proc/swaps":             "Filename Type Size Used Priority\n",
+		"/proc/meminfo":           "MemTotal: 16384000 kB\nMemAvailable: 8192000 kB\n",
+		"/sys/fs/selinux/enforce": "0",
+		"/dev/kvm":                "",
+	})
+	t.Setenv("container", "")
+	fakeCommand(t, map[string]string{
+		"docker info --format {{json .}}": `{"Name":"docker-desktop","OperatingSystem":"Docker Desktop","NCPU":4,"MemTotal":8589934592}`,
+	})
+
+	for name, check := range map[string]warningCheck{
+		"swap":           swapWarnings,
+		"selinux":        selinuxWarnings,
+		"kvm":            kvmWarnings,
+		"docker-desktop": dockerDesktopWarnings,
+	} {
+		if ws := check(context.Background()); len(ws) != 0 {
+			t.Errorf("%s check = %+v for a healthy host, want none", name, ws)
+		}
+	}
+	if runtime.GOOS == "linux" {
+		if ws := memoryWarnings(context.Background()); len(ws) != 0 {
+			t.Errorf("memory check = %+v for a healthy host, want none", ws)
+		}
+	}
+}
+
+func TestDetectionWarnings(t *testing.T) {
+	orig := warningChecks
+	defer func() { warningChecks = orig }()
+
+	var mu sync.Mutex
+	ran := 0
+	warningChecks = nil
+	for i := 0; i < 50; i++ {
+		i := i
+		warningChecks = append(warningChecks, func(context.Context) []Warning {
+			mu.Lock()
+			ran++
+			mu.Unlock()
+			return []Warning{{Detector: "test", Severity: Severity(i % 3), Message: fmt.Sprintf("problem %d", i%10)}}
+		})
+	}
+
+	ws := DetectionWarnings()
+	if ran != 50 {
+		t.Errorf("DetectionWarnings() ran %d checks, want 50", ran)
+	}
+	// repeated reports of the same problem are collapsed
+	if len(ws) != 10 {
+		t.Errorf("DetectionWarnings() returned %d warnings, want 10", len(ws))
+	}
+	for i := 1; i < len(ws); i++ {
+		if ws[i].Severity > ws[i-1].Severity {
+			t.Errorf("DetectionWarnings() is not sorted by severity: %+v", ws)
+			break
+		}
+	}
+	if again := DetectionWarnings(); !reflect.DeepEqual(again, ws) {
+		t.Errorf("DetectionWarnings() = %+v, then %+v, want the same result for the same checks", ws, again)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WSLVersion returns 1 or 2 for the version of the Windows Subsystem for Linux the process is running in,
+// or 0 when not running in WSL. Unlike WSL1, WSL2 runs a real Linux kernel and can run the docker driver.
+func WSLVersion() int {
+	if runtime.GOOS != "linux" {
+		return 0
+	}
+	return wslVersion()
+}
+
+func wslVersion() int {
+	v, err := os.ReadFile(hostPath("/proc/version"))
+	if err != nil || !strings.Contains(strings.ToLower(string(v)), "microsoft") {
+		return 0
+	}
+	r, err := os.ReadFile(hostPath("/proc/sys/kernel/osrelease"))
+	if err != nil {
+		return 0
+	}
+	release := strings.TrimSpace(string(r))
+	switch {
+	// WSL2 kernels are named like 5.15.90.1-microsoft-standard-WSL2, early ones lack the WSL2 suffix
+	case strings.Contains(release, "WSL2"), strings.Contains(release, "microsoft-standard"):
+		return 2
+	// WSL1 reports the Windows build as a fake kernel, like 4.4.0-19041-Microsoft
+	case strings.Contains(release, "Microsoft"):
+		return 1
+	}
+	return 0
+}
+
+// HasWSLg returns true if running in WSL2 with WSLg, so that GUI applications such as a browser can be opened
+func HasWSLg() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	return hasWSLg()
+}
+
+func hasWSLg() bool {
+	if wslVersion() != 2 {
+		return false
+	}
+	if fileExists("/mnt/wslg") {
+		return true
+	}
+	// WSLg links the X11 socket directory into /mnt/wslg
+	if os.Getenv("DISPLAY") == "" {
+		return false
+	}
+	target, err := os.Readlink(hostPath("/tmp/.X11-unix"))
+	return err == nil && strings.HasPrefix(filepath.ToSlash(target), "/mnt/wslg/")
+}
+
+// WSLMemoryLimitMiB returns the memory available to the WSL2 VM in MiB. The bool reports whether the limit
+// was explicitly set by the memory option of the Windows user's .wslconfig, otherwise the observed total is returned.
+// Outside WSL2 it returns 0 and false.
+func WSLMemoryLimitMiB() (int, bool) {
+	if runtime.GOOS != "linux" {
+		return 0, false
+	}
+	return wslMemoryLimitMiB()
+}
+
+func wslMemoryLimitMiB() (int, bool) {
+	if wslVersion() != 2 {
+		return 0, false
+	}
+	if profile := wslUserProfile(); profile != "" {
+		if b, err := os.ReadFile(hostPath(profile + "/.wslconfig")); err == nil {
+			if limit, ok := parseWSLConfigMemory(string(b)); ok {
+				return limit, true
+			}
+		}
+	}
+	return meminfoMiB("MemTotal"), false
+}
+
+// wslInteropTimeout bounds how long wslUserProfile waits for a Windows program, which hangs when WSL interop is broken
+const wslInteropTimeout = 5 * time.Second
+
+// wslUserProfile returns the WSL path of the Windows user's profile directory, like /mnt/c/Users/jdoe
+func wslUserProfile() string {
+	out, err := runCommandTimeout(context.Background(), wslInteropTimeout, "cmd.exe", "/c", "echo %USERPROFILE%")
+	if err == nil {
+		if p := windowsToWSLPath(strings.TrimSpace(string(out))); p != "" {
+			return p
+		}
+	}
+	// cmd.exe is unavailable when interop is disabled, assume the Windows and Linux user names match
+	if user := os.Getenv("USER"); user != "" {
+		return "/mnt/c/Users/" + user
+	}
+	return ""
+}
+
+// windowsToWSLPath converts an absolute Windows path such as C:\Users\jdoe to its default WSL mount, /mnt/c/Users/jdoe
+func windowsToWSLPath(p string) string {
+	if len(p) < 3 || p[1] != ':' || (p[2] != '\\' && p[2] != '/') {
+		return ""
+	}
+	return "/mnt/" + strings.ToLower(p[:1]) + strings.ReplaceAll(p[2:], "\\", "/")
+}
+
+// parseWSLConfigMemory returns the memory option of the [wsl2] section of a .wslconfig file in MiB
+func parseWSLConfigMemory(config string) (int, bool) {
+	section := ""
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		k, v, found := strings.Cut(line, "=")
+		if !found || section != "wsl2" || !strings.EqualFold(strings.TrimSpace(k), "memory") {
+			continue
+		}
+		// values may carry a trailing comment
+		if i := strings.IndexAny(v, "#;"); i >= 0 {
+			v = v[:i]
+		}
+		return parseMemorySizeMiB(v)
+	}
+	return 0, false
+}
+
+// parseMemorySizeMiB parses sizes such as "8GB", "512M" or a number of bytes into MiB, using binary units like WSL does
+func parseMemorySizeMiB(s string) (int, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+	shift := 0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		case 'T':
+			shift = 40
+		}
+	}
+	if shift > 0 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	mib := int(n * float64(uint64(1)<<shift) / (1 << 20))
+	if mib == 0 {
+		// below 1 MiB, e.g. "512K", is no usable limit
+		return 0, false
+	}
+	return mib, true
+}
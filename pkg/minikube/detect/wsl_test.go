@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	wsl1OSRelease = "4.4.0-19041-Microsoft\n"
+	wsl2OSRelease = "5.15.90.1-microsoft-standard-WSL2\n"
+)
+
+// fakeWSL sets up a host root with the /proc files of the given WSL kernel release
+func fakeWSL(t *testing.T, osrelease string, files map[string]string) string {
+	t.Helper()
+	all := map[string]string{
+		"/proc/version":              "Linux version " + osrelease,
+		"/proc/sys/kernel/osrelease": osrelease,
+	}
+	for k, v := range files {
+		all[k] = v
+	}
+	return fakeHostRoot(t, all)
+}
+
+func TestWSLVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		osrelease string
+		want      int
+	}{
+		{
+			name:      "wsl1",
+			version:   "Linux version 4.4.0-19041-Microsoft (Microsoft@Microsoft.com) (gcc version 5.4.0 (GCC) ) #1237-Microsoft Sat Sep 11 14:32:00 PST 2021\n",
+			osrelease: "4.4.0-19041-Microsoft\n",
+			want:      1,
+		},
+		{
+			name:      "wsl2",
+			version:   "Linux version 5.15.90.1-microsoft-standard-WSL2 (oe-user@oe-host) (x86_64-msft-linux-gcc (GCC) 9.3.0, GNU ld (GNU Binutils) 2.34.0.20200220) #1 SMP Fri Jan 27 02:56:13 UTC 2023\n",
+			osrelease: "5.15.90.1-microsoft-standard-WSL2\n",
+			want:      2,
+		},
+		{
+			name:      "early wsl2",
+			version:   "Linux version 4.19.128-microsoft-standard (oe-user@oe-host) (gcc version 8.2.0 (GCC)) #1 SMP Tue Jun 23 12:58:10 UTC 2020\n",
+			osrelease: "4.19.128-microsoft-standard\n",
+			want:      2,
+		},
+		{
+			name:      "linux",
+			version:   "Linux version 6.1.0-13-amd64 (debian-kernel@lists.debian.org) (gcc-12 (Debian 12.2.0-14) 12.2.0, GNU ld (GNU Binutils for Debian) 2.40) #1 SMP PREEMPT_DYNAMIC Debian 6.1.55-1 (2023-09-29)\n",
+			osrelease: "6.1.0-13-amd64\n",
+			want:      0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostRoot(t, map[string]string{
+				"/proc/version":              tc.version,
+				"/proc/sys/kernel/osrelease": tc.osrelease,
+			})
+			if got := wslVersion(); got != tc.want {
+				t.Errorf("wslVersion() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasWSLg(t *testing.T) {
+	t.Run("wslg mount", func(t *testing.T) {
+		t.Setenv("DISPLAY", "")
+		fakeWSL(t, wsl2OSRelease, map[string]string{"/mnt/wslg/weston.log": ""})
+		if !hasWSLg() {
+			t.Error("hasWSLg() = false, want true with /mnt/wslg")
+		}
+	})
+
+	t.Run("display socket", func(t *testing.T) {
+		t.Setenv("DISPLAY", ":0")
+		root := fakeWSL(t, wsl2OSRelease, nil)
+		if err := os.MkdirAll(filepath.Join(root, "tmp"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("/mnt/wslg/.X11-unix", filepath.Join(root, "tmp", ".X11-unix")); err != nil {
+			t.Fatal(err)
+		}
+		if !hasWSLg() {
+			t.Error("hasWSLg() = false, want true with DISPLAY on the WSLg socket")
+		}
+	})
+
+	t.Run("wsl2 without wslg", func(t *testing.T) {
+		t.Setenv("DISPLAY", ":0")
+		fakeWSL(t, wsl2OSRelease, nil)
+		if hasWSLg() {
+			t.Error("hasWSLg() = true, want false")
+		}
+	})
+
+	t.Run("wsl1", func(t *testing.T) {
+		fakeWSL(t, wsl1OSRelease, map[string]string{"/mnt/wslg/weston.log": ""})
+		if hasWSLg() {
+			t.Error("hasWSLg() = true, want false on WSL1")
+		}
+	})
+}
+
+func TestParseWSLConfigMemory(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   int
+		ok     bool
+	}{
+		{"gigabytes", "[wsl2]\nmemory=8GB\nprocessors=4\n", 8192, true},
+		{"megabytes", "[wsl2]\r\nmemory = 4096MB\r\n", 4096, true},
+		{"short suffix", "[wsl2]\nmemory=6G\n", 6144, true},
+		{"fractional", "[wsl2]\nmemory=1.5GB\n", 1536, true},
+		{"bytes", "[wsl2]\nmemory=2147483648\n", 2048, true},
+		{"trailing comment", "[wsl2]\nmemory=4GB # limit the VM\n", 4096, true},
+		{"case insensitive", "[WSL2]\nMemory=2gb\n", 2048, true},
+		{"other section", "[experimental]\nmemory=4GB\n[wsl2]\nswap=0\n", 0, false},
+		{"commented out", "[wsl2]\n# memory=4GB\n", 0, false},
+		{"invalid", "[wsl2]\nmemory=lots\n", 0, false},
+		{"below a mebibyte", "[wsl2]\nmemory=512KB\n", 0, false},
+		{"bytes below a mebibyte", "[wsl2]\nmemory=1048575\n", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseWSLConfigMemory(tc.config)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("parseWSLConfigMemory() = %d, %v, want %d, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestWSLMemoryLimitMiB(t *testing.T) {
+	meminfo := "MemTotal:        8029032 kB\nMemFree:         6562080 kB\n"
+
+	t.Run("wslconfig", func(t *testing.T) {
+		fakeWSL(t, wsl2OSRelease, map[string]string{
+			"/proc/meminfo":                meminfo,
+			"/mnt/c/Users/jdoe/.wslconfig": "[wsl2]\nmemory=4GB\n",
+		})
+		fakeCommand(t, map[string]string{"cmd.exe /c echo %USERPROFILE%": "C:\\Users\\jdoe\r\n"})
+		if got, ok := wslMemoryLimitMiB(); got != 4096 || !ok {
+			t.Errorf("wslMemoryLimitMiB() = %d, %v, want 4096, true", got, ok)
+		}
+	})
+
+	t.Run("no wslconfig", func(t *testing.T) {
+		t.Setenv("USER", "jdoe")
+		fakeWSL(t, wsl2OSRelease, map[string]string{"/proc/meminfo": meminfo})
+		fakeCommand(t, nil)
+		if got, ok := wslMemoryLimitMiB(); got != 7840 || ok {
+			t.Errorf("wslMemoryLimitMiB() = %d, %v, want 7840, false", got, ok)
+		}
+	})
+
+	t.Run("wsl1", func(t *testing.T) {
+		fakeWSL(t, wsl1OSRelease, map[string]string{"/proc/meminfo": meminfo})
+		if got, ok := wslMemoryLimitMiB(); got != 0 || ok {
+			t.Errorf("wslMemoryLimitMiB() = %d, %v, want 0, false", got, ok)
+		}
+	})
+}
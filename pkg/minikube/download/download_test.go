@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/detect"
 )
 
 // Force download tests to run in serial.
@@ -32,6 +34,8 @@ func TestDownload(t *testing.T) {
 	t.Run("BinaryDownloadPreventsMultipleDownload", testBinaryDownloadPreventsMultipleDownload)
 	t.Run("PreloadDownloadPreventsMultipleDownload", testPreloadDownloadPreventsMultipleDownload)
 	t.Run("ImageToCache", testImageToCache)
+	t.Run("ImageToCacheHitIgnoresCacheLock", testImageToCacheHitIgnoresCacheLock)
+	t.Run("ImageExistsInCacheWithoutChecksum", testImageExistsInCacheWithoutChecksum)
 	t.Run("ImageToDaemon", testImageToDaemon)
 	t.Run("PreloadNotExists", testPreloadNotExists)
 	t.Run("PreloadChecksumMismatch", testPreloadChecksumMismatch)
@@ -184,6 +188,52 @@ func testImageToCache(t *testing.T) {
 	}
 }
 
+func testImageToCacheHitIgnoresCacheLock(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+	checkImageExistsInCache = func(img string) bool { return true }
+
+	// a cache maintenance operation holding the cache lock must not stall cache hits
+	unlock, err := detect.AcquireCacheLock()
+	if err != nil {
+		t.Fatalf("AcquireCacheLock() returned an error: %v", err)
+	}
+	defer unlock()
+
+	done := make(chan error, 1)
+	go func() { done <- ImageToCache("testimg") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ImageToCache() returned an error on a cache hit: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ImageToCache() waited for the cache lock on a cache hit")
+	}
+}
+
+func testImageExistsInCacheWithoutChecksum(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+	img := "gcr.io/k8s-minikube/kicbase:v0.0.36"
+	f := imagePathInCache(img)
+	if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// a tarball without a checksum, as left by an interrupted pull
+	if err := os.WriteFile(f, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ImageExistsInCache(img) {
+		t.Error("ImageExistsInCache() = true for a tarball without a checksum")
+	}
+
+	if err := detect.WriteArtifactChecksum(f); err != nil {
+		t.Fatal(err)
+	}
+	if !ImageExistsInCache(img) {
+		t.Error("ImageExistsInCache() = false for a tarball with its checksum")
+	}
+}
+
 func testImageToDaemon(t *testing.T) {
 	downloadNum := 0
 	DownloadMock = mockSleepDownload(&downloadNum)
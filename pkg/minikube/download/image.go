@@ -60,6 +60,10 @@ func ImageExistsInCache(img string) bool {
 	klog.Infof("Checking for %s in local cache directory", img)
 	if st, err := os.Stat(f); err == nil {
 		if st.Size() > 0 {
+			if ok, err := detect.VerifyCachedArtifact(f, ""); err != nil || !ok {
+				klog.Warningf("%s in local cache directory is corrupted, pulling it again: %v", img, err)
+				return false
+			}
 			klog.Infof("Found %s in local cache directory, skipping pull", img)
 			return true
 		}
@@ -154,8 +158,10 @@ func ImageToCache(img string) error {
 	// Just a hair less than 80 (standard terminal width) for aesthetics & pasting into docs
 	p.SetWidth(79)
 
+	// write next to the cache entry and rename it into place, so that an interrupted pull leaves no partial tarball behind
+	tmp := f + ".download"
 	go func() {
-		err = tarball.WriteToFile(f, tag, i, tarball.WithProgress(c))
+		err = tarball.WriteToFile(tmp, tag, i, tarball.WithProgress(c))
 		errchan <- err
 	}()
 	var update v1.Update
@@ -167,8 +173,15 @@ func ImageToCache(img string) error {
 		case err = <-errchan:
 			p.Finish()
 			if err != nil {
+				os.Remove(tmp)
 				return errors.Wrap(err, "writing tarball image")
 			}
+			if err := os.Rename(tmp, f); err != nil {
+				return errors.Wrap(err, "renaming tarball image")
+			}
+			if err := detect.WriteArtifactChecksum(f); err != nil {
+				klog.Warningf("failed to store the checksum of %s: %v", f, err)
+			}
 			return nil
 		}
 	}
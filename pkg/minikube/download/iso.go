@@ -130,7 +130,12 @@ func downloadISO(isoURL string, skipChecksum bool) error {
 	defer releaser.Release()
 
 	if _, err := os.Stat(dst); err == nil {
-		return nil
+		ok, err := detect.VerifyCachedArtifact(dst, "")
+		if err == nil && ok {
+			return nil
+		}
+		// a corrupted ISO is a cache miss, download it again
+		klog.Warningf("cached ISO %s is corrupted, downloading it again: %v", dst, err)
 	}
 
 	out.Step(style.ISODownload, "Downloading VM boot image ...")
@@ -140,5 +145,11 @@ func downloadISO(isoURL string, skipChecksum bool) error {
 		urlWithChecksum = isoURL
 	}
 
-	return download(urlWithChecksum, dst)
+	if err := download(urlWithChecksum, dst); err != nil {
+		return err
+	}
+	if err := detect.WriteArtifactChecksum(dst); err != nil {
+		klog.Warningf("failed to store the checksum of %s: %v", dst, err)
+	}
+	return nil
 }
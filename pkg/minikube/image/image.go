@@ -274,8 +274,9 @@ func fixPlatform(ref name.Reference, img v1.Image, p v1.Platform) (v1.Image, err
 	return img, nil
 }
 
+// cleanImageCacheDir removes the empty directories of the image cache, across architectures and the legacy layout
 func cleanImageCacheDir() error {
-	err := filepath.Walk(localpath.MakeMiniPath("cache", "images"), func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(filepath.Join(detect.CacheDir(), string(detect.CacheImages)), func(path string, info os.FileInfo, err error) error {
 		// If error is not nil, it's because the path was already deleted and doesn't exist
 		// Move on to next path
 		if err != nil {
@@ -16,7 +16,14 @@ limitations under the License.
 
 package image
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/detect"
+)
 
 func TestTag(t *testing.T) {
 	tcs := []struct {
@@ -91,3 +98,44 @@ func TestNormalizeImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestCleanImageCacheDirOverride(t *testing.T) {
+	t.Setenv(constants.MinikubeCacheDirEnv, t.TempDir())
+	empty := filepath.Join(detect.ImageCacheDir(), "registry.k8s.io", "pause_3.9")
+	if err := os.MkdirAll(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	otherArch, err := detect.ImageCacheDirForArch("s390x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherArch = filepath.Join(otherArch, "registry.k8s.io", "pause_3.9")
+	legacy := filepath.Join(detect.CacheDir(), string(detect.CacheImages), "registry.k8s.io", "pause_3.8")
+	for _, dir := range []string{otherArch, legacy} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept := filepath.Join(detect.ImageCacheDir(), "docker.io", "busybox_latest")
+	if err := os.MkdirAll(filepath.Dir(kept), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(kept, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cleanImageCacheDir(); err != nil {
+		t.Fatalf("cleanImageCacheDir() = %v", err)
+	}
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("empty directory %s was not removed from the overridden cache: %v", empty, err)
+	}
+	for _, dir := range []string{otherArch, legacy} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("empty directory %s outside the cache of the current architecture was not removed: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("cached image %s was removed: %v", kept, err)
+	}
+}
@@ -21,11 +21,11 @@ package none
 import (
 	"fmt"
 	"os/exec"
-	"os/user"
 
 	"github.com/docker/machine/libmachine/drivers"
 	"k8s.io/minikube/pkg/drivers/none"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/registry"
@@ -59,12 +59,7 @@ func status() registry.State {
 		return registry.State{Running: true, Error: err, Fix: "iptables must be installed", Doc: "https://minikube.sigs.k8s.io/docs/reference/drivers/none/"}
 	}
 
-	u, err := user.Current()
-	if err != nil {
-		return registry.State{Running: true, Error: err, Healthy: false, Doc: "https://minikube.sigs.k8s.io/docs/reference/drivers/none/"}
-	}
-
-	if u.Uid != "0" {
+	if !detect.IsElevated() {
 		test := exec.Command("sudo", "-n", "echo", "-n")
 		if err := test.Run(); err != nil {
 			return registry.State{Error: fmt.Errorf("running the 'none' driver as a regular user requires sudo permissions"), Healthy: false}